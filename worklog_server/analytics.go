@@ -0,0 +1,449 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// days of the flow times window when from isn't given
+const defaultFlowWindowDays = 90
+
+// storage of the data behind the flow analytics
+type AnalyticsStore interface {
+	GetFlowTimes(options FlowTimeOptions) ([]FlowTime, error)
+}
+
+// percentile p (0 to 100) of sorted values, interpolated between the two
+// closest ranks
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// average and percentiles of a list of durations, in seconds
+type DurationStats struct {
+	Count      int     `json:"count"`
+	AvgSeconds float64 `json:"avgSeconds"`
+	P50Seconds float64 `json:"p50Seconds"`
+	P85Seconds float64 `json:"p85Seconds"`
+	P95Seconds float64 `json:"p95Seconds"`
+}
+
+func durationStats(seconds []float64) DurationStats {
+	stats := DurationStats{Count: len(seconds)}
+	if len(seconds) == 0 {
+		return stats
+	}
+
+	sorted := append([]float64{}, seconds...)
+	sort.Float64s(sorted)
+
+	total := 0.0
+	for _, value := range sorted {
+		total += value
+	}
+
+	stats.AvgSeconds = total / float64(len(sorted))
+	stats.P50Seconds = percentile(sorted, 50)
+	stats.P85Seconds = percentile(sorted, 85)
+	stats.P95Seconds = percentile(sorted, 95)
+	return stats
+}
+
+// a stay of a log in a status, end is nil while the log is still in it
+type statusPeriod struct {
+	logId  string
+	status string
+	start  time.Time
+	end    *time.Time
+}
+
+// stays in each status out of the transitions, ordered by log and time
+func statusPeriods(transitions []StatusTransition) []statusPeriod {
+	periods := []statusPeriod{}
+	for i, transition := range transitions {
+		period := statusPeriod{logId: transition.LogId, status: transition.ToStatus, start: transition.TransitionedAt}
+		if i+1 < len(transitions) && transitions[i+1].LogId == transition.LogId {
+			end := transitions[i+1].TransitionedAt
+			period.end = &end
+		}
+
+		periods = append(periods, period)
+	}
+
+	return periods
+}
+
+// time spent in a status. Only finished stays are in the stats, logs still
+// in the status are counted apart
+type StatusDuration struct {
+	TaskStatus string `json:"taskStatus"`
+	DurationStats
+	CurrentCount int `json:"currentCount"`
+}
+
+// durations of the stays that started between from and to, both optional.
+// The statuses are in the order of the vocabulary
+func statusDurations(transitions []StatusTransition, from *time.Time, to *time.Time, vocabulary Vocabulary) []StatusDuration {
+	seconds := map[string][]float64{}
+	current := map[string]int{}
+	for _, period := range statusPeriods(transitions) {
+		if (from != nil && period.start.Before(*from)) || (to != nil && period.start.After(*to)) {
+			continue
+		}
+
+		if period.end == nil {
+			current[period.status]++
+			if _, ok := seconds[period.status]; !ok {
+				seconds[period.status] = []float64{}
+			}
+
+			continue
+		}
+
+		seconds[period.status] = append(seconds[period.status], period.end.Sub(period.start).Seconds())
+	}
+
+	statuses := []string{}
+	for status := range seconds {
+		statuses = append(statuses, status)
+	}
+
+	sortByTerms(statuses, vocabulary.TaskStatuses)
+	durations := []StatusDuration{}
+	for _, status := range statuses {
+		durations = append(durations, StatusDuration{TaskStatus: status, DurationStats: durationStats(seconds[status]), CurrentCount: current[status]})
+	}
+
+	return durations
+}
+
+func handleGetStatusDurations(store Store, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vocabulary, ok := loadVocabulary(store, w)
+	if !ok {
+		return
+	}
+
+	filters, err := parseLogFilters(r.URL.Query(), vocabulary)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	if !validateProjectScope(store, w, filters) {
+		return
+	}
+
+	location, err := parseTimezone(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	// stays that started in the date range, plain dates are days in the
+	// timezone
+	from, err := parseFilterTime(r.URL.Query(), "from", false, location)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	to, err := parseFilterTime(r.URL.Query(), "to", true, location)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	transitions, err := store.GetStatusTransitions(filters)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while fetching status durations"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		StatusDurations []StatusDuration `json:"statusDurations"`
+	}{StatusDurations: statusDurations(transitions, from, to, vocabulary)})
+}
+
+// options of the flow times, the logs completed between from and to
+type FlowTimeOptions struct {
+	LogFilters
+	from time.Time
+	to   time.Time
+}
+
+// lead and cycle time of a completed log. Lead time runs from created, or
+// started when it is earlier, to completed. Cycle time runs from started to
+// completed and is nil for logs that were never started. A time is nil as
+// well when completed is before its start, like a log created with a
+// backdated completion
+type FlowTime struct {
+	LogId            string     `json:"logId"`
+	TaskName         string     `json:"taskName"`
+	TaskType         string     `json:"taskType"`
+	Priority         int        `json:"priority"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	StartedAt        *time.Time `json:"startedAt"`
+	CompletedAt      time.Time  `json:"completedAt"`
+	LeadTimeSeconds  *float64   `json:"leadTimeSeconds"`
+	CycleTimeSeconds *float64   `json:"cycleTimeSeconds"`
+}
+
+// percentiles of the lead and cycle times of a group of logs, the key is the
+// task type or priority of the group
+type FlowTimeStats struct {
+	Key       string        `json:"key,omitempty"`
+	Label     string        `json:"label,omitempty"`
+	LeadTime  DurationStats `json:"leadTime"`
+	CycleTime DurationStats `json:"cycleTime"`
+}
+
+// a point of the scatter chart, a log at the time it was completed
+type ScatterPoint struct {
+	LogId       string    `json:"logId"`
+	CompletedAt time.Time `json:"completedAt"`
+	Seconds     float64   `json:"seconds"`
+}
+
+// points of the scatter chart of each time
+type FlowScatter struct {
+	LeadTime  []ScatterPoint `json:"leadTime"`
+	CycleTime []ScatterPoint `json:"cycleTime"`
+}
+
+// logs in a terminal status completed in the window, ordered by completion
+func flowTimesQuery(options FlowTimeOptions) (string, []any) {
+	conditions, args := options.LogFilters.conditions(3)
+	conditions = append(conditions,
+		"logs.task_status in ("+terminalStatusesQuery+")",
+		"logs.completed_at >= $1",
+		"logs.completed_at <= $2",
+	)
+
+	args = append([]any{options.from, options.to}, args...)
+	q := `
+		select log_id, task_name, task_type, priority, created_at, started_at, completed_at
+		from logs
+		` + whereClause(conditions) + `
+		order by completed_at, log_id`
+
+	return q, args
+}
+
+// fill the lead and cycle times out of the timestamps
+func (flowTime FlowTime) withDurations() FlowTime {
+	since := func(start time.Time) *float64 {
+		if flowTime.CompletedAt.Before(start) {
+			return nil
+		}
+
+		seconds := flowTime.CompletedAt.Sub(start).Seconds()
+		return &seconds
+	}
+
+	leadStart := flowTime.CreatedAt
+	if flowTime.StartedAt != nil && flowTime.StartedAt.Before(leadStart) {
+		leadStart = *flowTime.StartedAt
+	}
+
+	flowTime.LeadTimeSeconds = since(leadStart)
+	flowTime.CycleTimeSeconds = nil
+	if flowTime.StartedAt != nil {
+		flowTime.CycleTimeSeconds = since(*flowTime.StartedAt)
+	}
+
+	return flowTime
+}
+
+func scanFlowTimes(rows *sql.Rows) ([]FlowTime, error) {
+	defer rows.Close()
+	flowTimes := []FlowTime{}
+	for rows.Next() {
+		var flowTime FlowTime
+		var startedAt sql.NullTime
+		if err := rows.Scan(&flowTime.LogId, &flowTime.TaskName, &flowTime.TaskType, &flowTime.Priority, &flowTime.CreatedAt, &startedAt, &flowTime.CompletedAt); err != nil {
+			return nil, err
+		}
+
+		if startedAt.Valid {
+			flowTime.StartedAt = &startedAt.Time
+		}
+
+		flowTimes = append(flowTimes, flowTime.withDurations())
+	}
+
+	return flowTimes, rows.Err()
+}
+
+func flowTimeStats(flowTimes []FlowTime) FlowTimeStats {
+	leadTimes := []float64{}
+	cycleTimes := []float64{}
+	for _, flowTime := range flowTimes {
+		if flowTime.LeadTimeSeconds != nil {
+			leadTimes = append(leadTimes, *flowTime.LeadTimeSeconds)
+		}
+
+		if flowTime.CycleTimeSeconds != nil {
+			cycleTimes = append(cycleTimes, *flowTime.CycleTimeSeconds)
+		}
+	}
+
+	return FlowTimeStats{LeadTime: durationStats(leadTimes), CycleTime: durationStats(cycleTimes)}
+}
+
+// stats of the flow times grouped by task type, in the vocabulary order, or
+// by priority, from the highest
+func flowTimeBreakdown(flowTimes []FlowTime, kind string, vocabulary Vocabulary) []FlowTimeStats {
+	groups := map[string][]FlowTime{}
+	keys := []string{}
+	for _, flowTime := range flowTimes {
+		key := flowTime.TaskType
+		if kind == "priority" {
+			key = strconv.Itoa(flowTime.Priority)
+		}
+
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+
+		groups[key] = append(groups[key], flowTime)
+	}
+
+	if kind == "priority" {
+		sort.Slice(keys, func(i, j int) bool {
+			a, _ := strconv.Atoi(keys[i])
+			b, _ := strconv.Atoi(keys[j])
+			return a > b
+		})
+	} else {
+		sortByTerms(keys, vocabulary.TaskTypes)
+	}
+
+	breakdown := []FlowTimeStats{}
+	for _, key := range keys {
+		stats := flowTimeStats(groups[key])
+		stats.Key = key
+		stats.Label = vocabulary.label(kind, key)
+		breakdown = append(breakdown, stats)
+	}
+
+	return breakdown
+}
+
+func handleGetFlowTimes(store Store, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vocabulary, ok := loadVocabulary(store, w)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filters, err := parseLogFilters(query, vocabulary)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	if !validateProjectScope(store, w, filters) {
+		return
+	}
+
+	location, err := parseTimezone(query)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	// the window is on the completion time, the last 90 days by default
+	from, err := parseFilterTime(query, "from", false, location)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	to, err := parseFilterTime(query, "to", true, location)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	options := FlowTimeOptions{LogFilters: filters, to: time.Now().UTC()}
+	if to != nil {
+		options.to = *to
+	}
+
+	options.from = truncateDate(options.to.In(location).AddDate(0, 0, -defaultFlowWindowDays), "day").UTC()
+	if from != nil {
+		options.from = *from
+	}
+
+	if options.to.Before(options.from) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": "to can't be before from"})
+		return
+	}
+
+	flowTimes, err := store.GetFlowTimes(options)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while fetching flow times"})
+		return
+	}
+
+	scatter := FlowScatter{LeadTime: []ScatterPoint{}, CycleTime: []ScatterPoint{}}
+	for _, flowTime := range flowTimes {
+		if flowTime.LeadTimeSeconds != nil {
+			scatter.LeadTime = append(scatter.LeadTime, ScatterPoint{LogId: flowTime.LogId, CompletedAt: flowTime.CompletedAt, Seconds: *flowTime.LeadTimeSeconds})
+		}
+
+		if flowTime.CycleTimeSeconds != nil {
+			scatter.CycleTime = append(scatter.CycleTime, ScatterPoint{LogId: flowTime.LogId, CompletedAt: flowTime.CompletedAt, Seconds: *flowTime.CycleTimeSeconds})
+		}
+	}
+
+	total := flowTimeStats(flowTimes)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		From       time.Time       `json:"from"`
+		To         time.Time       `json:"to"`
+		Timezone   string          `json:"timezone"`
+		LeadTime   DurationStats   `json:"leadTime"`
+		CycleTime  DurationStats   `json:"cycleTime"`
+		ByType     []FlowTimeStats `json:"byType"`
+		ByPriority []FlowTimeStats `json:"byPriority"`
+		Logs       []FlowTime      `json:"logs"`
+		Scatter    FlowScatter     `json:"scatter"`
+	}{
+		From:       options.from.In(location),
+		To:         options.to.In(location),
+		Timezone:   location.String(),
+		LeadTime:   total.LeadTime,
+		CycleTime:  total.CycleTime,
+		ByType:     flowTimeBreakdown(flowTimes, "type", vocabulary),
+		ByPriority: flowTimeBreakdown(flowTimes, "priority", vocabulary),
+		Logs:       flowTimes,
+		Scatter:    scatter,
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []float64
+		p      float64
+		want   float64
+	}{
+		{"empty", []float64{}, 50, 0},
+		{"one value", []float64{7}, 95, 7},
+		{"lowest", []float64{1, 2, 3}, 0, 1},
+		{"highest", []float64{1, 2, 3}, 100, 3},
+		{"on a rank", []float64{1, 2, 3}, 50, 2},
+		{"between ranks", []float64{10, 20}, 85, 18.5},
+		{"between ranks of many", []float64{1, 2, 3, 4, 5}, 95, 4.8},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := percentile(test.sorted, test.p)
+			if diff := got - test.want; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("percentile(%v, %v) = %v, want %v", test.sorted, test.p, got, test.want)
+			}
+		})
+	}
+}
+
+func TestStatusPeriods(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 9, 0, 0, 0, time.UTC) }
+	end := func(d int) *time.Time { value := day(d); return &value }
+	backlog := "backlog"
+
+	tests := []struct {
+		name        string
+		transitions []StatusTransition
+		want        []statusPeriod
+	}{
+		{
+			name:        "empty",
+			transitions: []StatusTransition{},
+			want:        []statusPeriod{},
+		},
+		{
+			name:        "one transition is an open period",
+			transitions: []StatusTransition{{LogId: "a", ToStatus: "backlog", TransitionedAt: day(1)}},
+			want:        []statusPeriod{{logId: "a", status: "backlog", start: day(1)}},
+		},
+		{
+			name: "a period ends on the next transition of the log",
+			transitions: []StatusTransition{
+				{LogId: "a", ToStatus: "backlog", TransitionedAt: day(1)},
+				{LogId: "a", FromStatus: &backlog, ToStatus: "progress", TransitionedAt: day(3)},
+			},
+			want: []statusPeriod{
+				{logId: "a", status: "backlog", start: day(1), end: end(3)},
+				{logId: "a", status: "progress", start: day(3)},
+			},
+		},
+		{
+			name: "the last period of a log stays open before the next log",
+			transitions: []StatusTransition{
+				{LogId: "a", ToStatus: "backlog", TransitionedAt: day(1)},
+				{LogId: "b", ToStatus: "progress", TransitionedAt: day(2)},
+				{LogId: "b", ToStatus: "done", TransitionedAt: day(4)},
+			},
+			want: []statusPeriod{
+				{logId: "a", status: "backlog", start: day(1)},
+				{logId: "b", status: "progress", start: day(2), end: end(4)},
+				{logId: "b", status: "done", start: day(4)},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := statusPeriods(test.transitions)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("statusPeriods() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
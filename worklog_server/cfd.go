@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// longest date range of a cumulative flow diagram, in days
+const maxFlowDays = 366
+
+// days of a cumulative flow diagram when from isn't given
+const defaultFlowDays = 30
+
+// logs in a status at the end of each day of a cumulative flow diagram
+type FlowSeries struct {
+	TaskStatus string `json:"taskStatus"`
+	Label      string `json:"label"`
+	IsTerminal bool   `json:"isTerminal,omitempty"`
+	Counts     []int  `json:"counts"`
+}
+
+// count the logs in each status at every snapshot out of the transitions,
+// snapshots are in order. Every status of the vocabulary has a series, in
+// its order, with the unknown statuses after them
+func cumulativeFlow(transitions []StatusTransition, snapshots []time.Time, vocabulary Vocabulary) []FlowSeries {
+	counts := map[string][]int{}
+	for _, term := range vocabulary.TaskStatuses {
+		counts[term.Name] = make([]int, len(snapshots))
+	}
+
+	for _, period := range statusPeriods(transitions) {
+		// the log is in the status at the snapshots from first to last
+		first := sort.Search(len(snapshots), func(i int) bool { return !snapshots[i].Before(period.start) })
+		last := len(snapshots)
+		if period.end != nil {
+			last = sort.Search(len(snapshots), func(i int) bool { return !snapshots[i].Before(*period.end) })
+		}
+
+		if first >= last {
+			continue
+		}
+
+		if counts[period.status] == nil {
+			counts[period.status] = make([]int, len(snapshots))
+		}
+
+		for i := first; i < last; i++ {
+			counts[period.status][i]++
+		}
+	}
+
+	statuses := []string{}
+	for status := range counts {
+		statuses = append(statuses, status)
+	}
+
+	sortByTerms(statuses, vocabulary.TaskStatuses)
+	series := []FlowSeries{}
+	for _, status := range statuses {
+		label := status
+		if i := termPosition(vocabulary.TaskStatuses, status); i >= 0 {
+			label = vocabulary.TaskStatuses[i].Label
+		}
+
+		series = append(series, FlowSeries{TaskStatus: status, Label: label, IsTerminal: vocabulary.isTerminal(status), Counts: counts[status]})
+	}
+
+	return series
+}
+
+func handleGetCumulativeFlow(store Store, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vocabulary, ok := loadVocabulary(store, w)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filters, err := parseLogFilters(query, vocabulary)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	if !validateProjectScope(store, w, filters) {
+		return
+	}
+
+	location, err := parseTimezone(query)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	// the last 30 days up to today by default
+	from, err := parseDay(query, "from", location)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	to, err := parseDay(query, "to", location)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	lastDay := truncateDate(now.In(location), "day")
+	if to != nil {
+		lastDay = *to
+	}
+
+	firstDay := lastDay.AddDate(0, 0, 1-defaultFlowDays)
+	if from != nil {
+		firstDay = *from
+	}
+
+	if lastDay.Before(firstDay) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": "to can't be before from"})
+		return
+	}
+
+	if lastDay.After(firstDay.AddDate(0, 0, maxFlowDays-1)) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("the range can't be longer than %d days", maxFlowDays)})
+		return
+	}
+
+	// a day is counted at its end, or now for today
+	dates := []string{}
+	snapshots := []time.Time{}
+	for day := firstDay; !day.After(lastDay); day = day.AddDate(0, 0, 1) {
+		snapshot := day.AddDate(0, 0, 1)
+		if snapshot.After(now) {
+			snapshot = now
+		}
+
+		dates = append(dates, day.Format(time.DateOnly))
+		snapshots = append(snapshots, snapshot)
+	}
+
+	transitions, err := store.GetStatusTransitions(filters)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while building the cumulative flow"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		From     string       `json:"from"`
+		To       string       `json:"to"`
+		Timezone string       `json:"timezone"`
+		Dates    []string     `json:"dates"`
+		Series   []FlowSeries `json:"series"`
+	}{
+		From:     firstDay.Format(time.DateOnly),
+		To:       lastDay.Format(time.DateOnly),
+		Timezone: location.String(),
+		Dates:    dates,
+		Series:   cumulativeFlow(transitions, snapshots, vocabulary),
+	})
+}
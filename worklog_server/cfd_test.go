@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCumulativeFlow(t *testing.T) {
+	day := func(d int, hour int) time.Time { return time.Date(2024, 3, d, hour, 0, 0, 0, time.UTC) }
+	// end of the 1st, 2nd and 3rd of march
+	snapshots := []time.Time{day(2, 0), day(3, 0), day(4, 0)}
+	vocabulary := Vocabulary{TaskStatuses: []VocabularyTerm{
+		{Name: "backlog", Label: "Backlog", Position: 1},
+		{Name: "progress", Label: "Progress", Position: 2},
+		{Name: "done", Label: "Done", Position: 3, IsTerminal: true},
+	}}
+
+	series := func(backlog []int, progress []int, done []int) []FlowSeries {
+		return []FlowSeries{
+			{TaskStatus: "backlog", Label: "Backlog", Counts: backlog},
+			{TaskStatus: "progress", Label: "Progress", Counts: progress},
+			{TaskStatus: "done", Label: "Done", IsTerminal: true, Counts: done},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		transitions []StatusTransition
+		snapshots   []time.Time
+		want        []FlowSeries
+	}{
+		{
+			name:        "no transitions",
+			transitions: []StatusTransition{},
+			snapshots:   snapshots,
+			want:        series([]int{0, 0, 0}, []int{0, 0, 0}, []int{0, 0, 0}),
+		},
+		{
+			name:        "no snapshots",
+			transitions: []StatusTransition{{LogId: "a", ToStatus: "backlog", TransitionedAt: day(1, 9)}},
+			snapshots:   []time.Time{},
+			want:        series([]int{}, []int{}, []int{}),
+		},
+		{
+			name:        "an open period counts until the last snapshot",
+			transitions: []StatusTransition{{LogId: "a", ToStatus: "backlog", TransitionedAt: day(1, 9)}},
+			snapshots:   snapshots,
+			want:        series([]int{1, 1, 1}, []int{0, 0, 0}, []int{0, 0, 0}),
+		},
+		{
+			name: "a period ending on a snapshot counts in the next status",
+			transitions: []StatusTransition{
+				{LogId: "a", ToStatus: "backlog", TransitionedAt: day(1, 9)},
+				{LogId: "a", ToStatus: "progress", TransitionedAt: day(3, 0)},
+			},
+			snapshots: snapshots,
+			want:      series([]int{1, 0, 0}, []int{0, 1, 1}, []int{0, 0, 0}),
+		},
+		{
+			name: "a period between two snapshots isn't counted",
+			transitions: []StatusTransition{
+				{LogId: "a", ToStatus: "backlog", TransitionedAt: day(1, 9)},
+				{LogId: "a", ToStatus: "progress", TransitionedAt: day(2, 10)},
+				{LogId: "a", ToStatus: "done", TransitionedAt: day(2, 15)},
+			},
+			snapshots: snapshots,
+			want:      series([]int{1, 0, 0}, []int{0, 0, 0}, []int{0, 1, 1}),
+		},
+		{
+			name: "a log created after the snapshots isn't counted",
+			transitions: []StatusTransition{
+				{LogId: "a", ToStatus: "progress", TransitionedAt: day(5, 9)},
+			},
+			snapshots: snapshots,
+			want:      series([]int{0, 0, 0}, []int{0, 0, 0}, []int{0, 0, 0}),
+		},
+		{
+			name: "unknown statuses come after the vocabulary",
+			transitions: []StatusTransition{
+				{LogId: "a", ToStatus: "review", TransitionedAt: day(1, 9)},
+			},
+			snapshots: snapshots,
+			want: append(series([]int{0, 0, 0}, []int{0, 0, 0}, []int{0, 0, 0}),
+				FlowSeries{TaskStatus: "review", Label: "review", Counts: []int{1, 1, 1}}),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := cumulativeFlow(test.transitions, test.snapshots, vocabulary)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("cumulativeFlow() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// most buckets a completed count can have
+const maxCompletedBuckets = 1000
+
+// views of the completed count, the size of a bucket
+var completedViews = map[string]bool{"day": true, "week": true, "month": true, "quarter": true, "year": true}
+
+// sql of the column each breakdown splits the counts on
+var completedBreakdowns = map[string]string{
+	"":         "''",
+	"type":     "logs.task_type",
+	"priority": "cast(logs.priority as text)",
+}
+
+// options of a completed count. The logs completed between from and to are
+// counted in buckets of the view starting at midnight in the location
+type CompletedCountOptions struct {
+	LogFilters
+	view      string
+	from      time.Time
+	to        time.Time
+	location  *time.Location
+	breakdown string
+}
+
+// start of every bucket between from and to
+func (options CompletedCountOptions) buckets() []time.Time {
+	buckets := []time.Time{}
+	last := truncateDate(options.to.In(options.location), options.view)
+	for d := truncateDate(options.from.In(options.location), options.view); !d.After(last); d = addInterval(d, 1, options.view) {
+		buckets = append(buckets, d)
+	}
+
+	return buckets
+}
+
+// logs completed in a bucket, for one value of the breakdown
+type completedCountRow struct {
+	bucket time.Time
+	group  string
+	count  int
+}
+
+// completed counts of one value of the breakdown
+type CompletedSeries struct {
+	Key            string           `json:"key"`
+	Label          string           `json:"label"`
+	CompletedCount []CompletedCount `json:"completedCount"`
+}
+
+// read the options of a completed count. The range is either the last d
+// (like "3 months") or from and to, the last month by default
+func parseCompletedCountOptions(query url.Values, vocabulary Vocabulary) (CompletedCountOptions, error) {
+	var options CompletedCountOptions
+	var err error
+
+	options.LogFilters, err = parseLogFilters(query, vocabulary)
+	if err != nil {
+		return options, err
+	}
+
+	options.location, err = parseTimezone(query)
+	if err != nil {
+		return options, err
+	}
+
+	// v is the name used before view
+	options.view = query.Get("view")
+	if options.view == "" {
+		options.view = query.Get("v")
+	}
+
+	if options.view == "" {
+		options.view = "week"
+	}
+
+	if !completedViews[options.view] {
+		return options, fmt.Errorf("invalid view value %q, expected day, week, month, quarter or year", options.view)
+	}
+
+	options.breakdown = query.Get("breakdown")
+	if _, ok := completedBreakdowns[options.breakdown]; !ok {
+		return options, fmt.Errorf("invalid breakdown value %q, expected type or priority", options.breakdown)
+	}
+
+	duration := strings.TrimSpace(query.Get("d"))
+	from, err := parseFilterTime(query, "from", false, options.location)
+	if err != nil {
+		return options, err
+	}
+
+	to, err := parseFilterTime(query, "to", true, options.location)
+	if err != nil {
+		return options, err
+	}
+
+	if duration != "" && (from != nil || to != nil) {
+		return options, fmt.Errorf("use either d or from and to")
+	}
+
+	options.to = time.Now().UTC()
+	if to != nil {
+		options.to = *to
+	}
+
+	if from != nil {
+		options.from = *from
+	} else {
+		if duration == "" {
+			duration = "1 months"
+		}
+
+		n, unit, err := parseInterval(duration)
+		if err != nil {
+			return options, err
+		}
+
+		options.from = truncateDate(addInterval(options.to.In(options.location), -n, unit), options.view).UTC()
+	}
+
+	if options.to.Before(options.from) {
+		return options, fmt.Errorf("to can't be before from")
+	}
+
+	if len(options.buckets()) > maxCompletedBuckets {
+		return options, fmt.Errorf("the range has more than %d %s buckets", maxCompletedBuckets, options.view)
+	}
+
+	return options, nil
+}
+
+// counts of every bucket for each value of the breakdown, and their total
+func completedCountSeries(rows []completedCountRow, options CompletedCountOptions, vocabulary Vocabulary) ([]CompletedCount, []CompletedSeries) {
+	buckets := options.buckets()
+	counts := map[string]map[int64]int{}
+	for _, row := range rows {
+		if counts[row.group] == nil {
+			counts[row.group] = map[int64]int{}
+		}
+
+		counts[row.group][row.bucket.Unix()] += row.count
+	}
+
+	series := func(groups ...string) []CompletedCount {
+		completedCounts := []CompletedCount{}
+		for _, bucket := range buckets {
+			count := 0
+			for _, group := range groups {
+				count += counts[group][bucket.Unix()]
+			}
+
+			completedCounts = append(completedCounts, CompletedCount{CompletedAt: bucket, TaskCount: count})
+		}
+
+		return completedCounts
+	}
+
+	groups := []string{}
+	for group := range counts {
+		groups = append(groups, group)
+	}
+
+	total := series(groups...)
+	if options.breakdown == "" {
+		return total, nil
+	}
+
+	// types in the vocabulary order, priorities from the highest
+	switch options.breakdown {
+	case "type":
+		sortByTerms(groups, vocabulary.TaskTypes)
+	case "priority":
+		sort.Slice(groups, func(i, j int) bool {
+			a, _ := strconv.Atoi(groups[i])
+			b, _ := strconv.Atoi(groups[j])
+			return a > b
+		})
+	}
+
+	breakdown := []CompletedSeries{}
+	for _, group := range groups {
+		breakdown = append(breakdown, CompletedSeries{Key: group, Label: vocabulary.label(options.breakdown, group), CompletedCount: series(group)})
+	}
+
+	return total, breakdown
+}
+
+func handleGetCompletedTaskCount(store Store, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vocabulary, ok := loadVocabulary(store, w)
+	if !ok {
+		return
+	}
+
+	options, err := parseCompletedCountOptions(r.URL.Query(), vocabulary)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	if !validateProjectScope(store, w, options.LogFilters) {
+		return
+	}
+
+	rows, err := store.GetCompletedTaskCount(options)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while getting completed task count"})
+		return
+	}
+
+	total, series := completedCountSeries(rows, options, vocabulary)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Message        string            `json:"message"`
+		View           string            `json:"view"`
+		From           time.Time         `json:"from"`
+		To             time.Time         `json:"to"`
+		Timezone       string            `json:"timezone"`
+		CompletedCount []CompletedCount  `json:"completedCount"`
+		Breakdown      string            `json:"breakdown,omitempty"`
+		Series         []CompletedSeries `json:"series,omitempty"`
+	}{
+		Message:        "ok",
+		View:           options.view,
+		From:           options.from.In(options.location),
+		To:             options.to.In(options.location),
+		Timezone:       options.location.String(),
+		CompletedCount: total,
+		Breakdown:      options.breakdown,
+		Series:         series,
+	})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// position after the last log of a page, for keyset pagination.
+// Clients get it base64 encoded and must treat it as opaque
+type logCursor struct {
+	SortBy    string `json:"sortBy"`
+	SortOrder string `json:"sortOrder"`
+	Value     string `json:"value"`
+	LogId     string `json:"logId"`
+}
+
+// kind of value of the columns that can be used with a cursor,
+// nullable columns can't be compared as a row value so they are left out
+var cursorColumns = map[string]string{
+	"task_name":   "text",
+	"task_type":   "text",
+	"task_status": "text",
+	"priority":    "int",
+	"created_at":  "time",
+	"updated_at":  "time",
+}
+
+func encodeCursor(cursor logCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (*logCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	var cursor logCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || !uuidPattern.MatchString(cursor.LogId) {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	return &cursor, nil
+}
+
+// cursor pointing right after the given log
+func cursorAfter(workLog WorkLog, sortBy string, sortOrder string) logCursor {
+	cursor := logCursor{SortBy: sortBy, SortOrder: sortOrder, LogId: workLog.LogId}
+	switch sortBy {
+	case "task_name":
+		cursor.Value = workLog.TaskName
+	case "task_type":
+		cursor.Value = workLog.TaskType
+	case "task_status":
+		cursor.Value = workLog.TaskStatus
+	case "priority":
+		cursor.Value = strconv.Itoa(workLog.Priority)
+	case "created_at":
+		cursor.Value = workLog.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "updated_at":
+		cursor.Value = workLog.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	return cursor
+}
+
+// typed value of the cursor, to be bound to the keyset condition
+func (cursor logCursor) arg() (any, error) {
+	switch cursorColumns[cursor.SortBy] {
+	case "text":
+		return cursor.Value, nil
+	case "int":
+		return strconv.Atoi(cursor.Value)
+	case "time":
+		return time.Parse(time.RFC3339Nano, cursor.Value)
+	}
+
+	return nil, fmt.Errorf("invalid cursor")
+}
+
+// check that the cursor mode of the options can be served
+func (options GetAllLogsOpts) validateCursor() error {
+	if !options.cursorMode {
+		return nil
+	}
+
+	sortBy, sortOrder, err := options.orderBy()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := cursorColumns[sortBy]; !ok {
+		return fmt.Errorf("sortBy %s can't be used with a cursor", sortBy)
+	}
+
+	if options.cursor != nil {
+		if options.cursor.SortBy != sortBy || options.cursor.SortOrder != sortOrder {
+			return fmt.Errorf("cursor was created for a different sortBy or sortOrder")
+		}
+
+		if _, err := options.cursor.arg(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// keyset condition selecting the logs after the cursor, placeholders are
+// numbered from argIdx. Works as a row value comparison on postgres and sqlite
+func (options GetAllLogsOpts) keysetCondition(argIdx int) ([]string, []any, error) {
+	if options.cursor == nil {
+		return nil, nil, nil
+	}
+
+	sortBy, sortOrder, err := options.orderBy()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	value, err := options.cursor.arg()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	operator := ">"
+	if sortOrder == "desc" {
+		operator = "<"
+	}
+
+	condition := fmt.Sprintf("(logs.%s, logs.log_id) %s ($%d, $%d)", sortBy, operator, argIdx, argIdx+1)
+	return []string{condition}, []any{value, options.cursor.LogId}, nil
+}
+
+// trim the extra row fetched in cursor mode and point the next cursor at the
+// last log of the page
+func cursorPage(logs []WorkLog, options GetAllLogsOpts, limit int) (LogsPage, error) {
+	page := LogsPage{Logs: logs}
+	if len(logs) <= limit {
+		return page, nil
+	}
+
+	sortBy, sortOrder, err := options.orderBy()
+	if err != nil {
+		return page, err
+	}
+
+	page.Logs = logs[:limit]
+	page.NextCursor = encodeCursor(cursorAfter(page.Logs[limit-1], sortBy, sortOrder))
+	return page, nil
+}
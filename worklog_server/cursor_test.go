@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	valid := logCursor{SortBy: "priority", SortOrder: "asc", Value: "2", LogId: "0b5e4c1a-7f43-4d8e-9a53-3c0f7d2b6e11"}
+	tests := []struct {
+		name   string
+		value  string
+		want   *logCursor
+		wantOk bool
+	}{
+		{"round trip", encodeCursor(valid), &valid, true},
+		{"not base64", "not a cursor!", nil, false},
+		{"not json", "bm90IGpzb24", nil, false},
+		{"no log id", encodeCursor(logCursor{SortBy: "priority", SortOrder: "asc", Value: "2"}), nil, false},
+		{"log id that isn't a uuid", encodeCursor(logCursor{SortBy: "priority", SortOrder: "asc", Value: "2", LogId: "1' or '1'='1"}), nil, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := decodeCursor(test.value)
+			if (err == nil) != test.wantOk {
+				t.Fatalf("decodeCursor() error = %v, want ok %v", err, test.wantOk)
+			}
+
+			if test.want != nil && *got != *test.want {
+				t.Errorf("decodeCursor() = %+v, want %+v", *got, *test.want)
+			}
+		})
+	}
+}
+
+func TestGetAllLogsCursor(t *testing.T) {
+	store := newTestStore(t)
+	// most logs share a priority so the pages are split on the log id
+	priorities := []int{2, 1, 2, 2, 3, 2, 1}
+	logs := []WorkLog{}
+	for i, priority := range priorities {
+		logs = append(logs, createTestLog(t, store, CreateLogInput{TaskName: fmt.Sprintf("log %d", i), Priority: priority}))
+	}
+
+	for _, sortOrder := range []string{"asc", "desc"} {
+		t.Run(sortOrder, func(t *testing.T) {
+			want := append([]WorkLog{}, logs...)
+			sort.Slice(want, func(i, j int) bool {
+				if want[i].Priority != want[j].Priority {
+					return (want[i].Priority < want[j].Priority) == (sortOrder == "asc")
+				}
+
+				return (want[i].LogId < want[j].LogId) == (sortOrder == "asc")
+			})
+
+			options := GetAllLogsOpts{sortBy: "priority", sortOrder: sortOrder, limit: 2, cursorMode: true}
+			got := []WorkLog{}
+			for pages := 0; ; pages++ {
+				if pages > len(logs) {
+					t.Fatal("the cursor never reached the last page")
+				}
+
+				page, err := store.GetAllLogs(options)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got = append(got, page.Logs...)
+				if page.NextCursor == "" {
+					break
+				}
+
+				options.cursor, err = decodeCursor(page.NextCursor)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if len(got) != len(want) {
+				t.Fatalf("got %d logs, want %d", len(got), len(want))
+			}
+
+			for i := range want {
+				if got[i].LogId != want[i].LogId {
+					t.Errorf("log %d = %s (priority %d), want %s (priority %d)", i, got[i].LogId, got[i].Priority, want[i].LogId, want[i].Priority)
+				}
+			}
+		})
+	}
+}
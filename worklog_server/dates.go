@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
+)
+
+// read the IANA timezone of a request, DEFAULT_TIMEZONE without one. Days,
+// weeks and months of the analytics are bucketed in it
+func parseTimezone(query url.Values) (*time.Location, error) {
+	name := strings.TrimSpace(query.Get("tz"))
+	if name == "" {
+		name = GetSecrets().defaultTimezone
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tz value %q", name)
+	}
+
+	return location, nil
+}
+
+// parse a postgres style interval such as "1 months" or "2 weeks"
+func parseInterval(interval string) (int, string, error) {
+	parts := strings.Fields(strings.ToLower(interval))
+	if len(parts) != 2 {
+		return 0, "", fmt.Errorf("invalid interval %q", interval)
+	}
+
+	n, err := strconv.Atoi(parts[0])
+	if err != nil || n < 0 {
+		return 0, "", fmt.Errorf("invalid interval %q", interval)
+	}
+
+	unit := strings.TrimSuffix(parts[1], "s")
+	switch unit {
+	case "day", "week", "month", "quarter", "year":
+		return n, unit, nil
+	}
+
+	return 0, "", fmt.Errorf("invalid interval unit %q", parts[1])
+}
+
+// move t by n units of day, week, month, quarter or year
+func addInterval(t time.Time, n int, unit string) time.Time {
+	switch unit {
+	case "day":
+		return t.AddDate(0, 0, n)
+	case "week":
+		return t.AddDate(0, 0, 7*n)
+	case "month":
+		return t.AddDate(0, n, 0)
+	case "quarter":
+		return t.AddDate(0, 3*n, 0)
+	case "year":
+		return t.AddDate(n, 0, 0)
+	}
+
+	return t
+}
+
+// same as postgres date_trunc, weeks start on monday
+func truncateDate(t time.Time, unit string) time.Time {
+	year, month, day := t.Date()
+	switch unit {
+	case "week":
+		weekday := (int(t.Weekday()) + 6) % 7
+		return startOfDay(year, month, day-weekday, t.Location())
+	case "month":
+		return startOfDay(year, month, 1, t.Location())
+	case "quarter":
+		return startOfDay(year, month-(month-1)%3, 1, t.Location())
+	case "year":
+		return startOfDay(year, 1, 1, t.Location())
+	}
+
+	return startOfDay(year, month, day, t.Location())
+}
+
+// first instant of a day. Where the clocks go forward at midnight the day
+// starts when they do, like with date_trunc, instead of the day before
+func startOfDay(year int, month time.Month, day int, location *time.Location) time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, location)
+	if t.YearDay() != time.Date(year, month, day, 0, 0, 0, 0, time.UTC).YearDay() {
+		_, end := t.ZoneBounds()
+		return end
+	}
+
+	return t
+}
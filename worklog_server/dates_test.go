@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTruncateDate(t *testing.T) {
+	// the wanted values are the ones of postgres date_trunc on a timestamptz
+	// with the session timezone set to the location
+	tests := []struct {
+		name     string
+		location string
+		t        string
+		unit     string
+		want     string
+	}{
+		{"day", "UTC", "2024-05-17T15:04:05Z", "day", "2024-05-17T00:00:00Z"},
+		{"week from a monday", "UTC", "2024-01-01T10:00:00Z", "week", "2024-01-01T00:00:00Z"},
+		{"week from a sunday", "UTC", "2023-12-31T23:00:00Z", "week", "2023-12-25T00:00:00Z"},
+		{"week across a month", "UTC", "2024-03-02T08:00:00Z", "week", "2024-02-26T00:00:00Z"},
+		{"week across a year", "UTC", "2025-01-01T08:00:00Z", "week", "2024-12-30T00:00:00Z"},
+		{"month on its first instant", "UTC", "2024-04-01T00:00:00Z", "month", "2024-04-01T00:00:00Z"},
+		{"month on its last instant", "UTC", "2024-02-29T23:59:59Z", "month", "2024-02-01T00:00:00Z"},
+		{"quarter on its last instant", "UTC", "2024-03-31T23:59:59Z", "quarter", "2024-01-01T00:00:00Z"},
+		{"quarter on its first instant", "UTC", "2024-04-01T00:00:00Z", "quarter", "2024-04-01T00:00:00Z"},
+		{"last quarter", "UTC", "2024-12-31T12:00:00Z", "quarter", "2024-10-01T00:00:00Z"},
+		{"year on its last instant", "UTC", "2024-12-31T23:59:59Z", "year", "2024-01-01T00:00:00Z"},
+		{"year on its first instant", "UTC", "2025-01-01T00:00:00Z", "year", "2025-01-01T00:00:00Z"},
+		{"year of the local time", "America/New_York", "2025-01-01T03:00:00Z", "year", "2024-01-01T00:00:00-05:00"},
+		{"day of a spring forward", "America/New_York", "2024-03-10T12:00:00-04:00", "day", "2024-03-10T00:00:00-05:00"},
+		{"week over a spring forward", "America/New_York", "2024-03-10T12:00:00-04:00", "week", "2024-03-04T00:00:00-05:00"},
+		{"week over a fall back", "America/New_York", "2024-11-03T12:00:00-05:00", "week", "2024-10-28T00:00:00-04:00"},
+		{"month over a fall back", "America/New_York", "2024-11-30T23:00:00-05:00", "month", "2024-11-01T00:00:00-04:00"},
+		{"quarter over a spring forward", "Europe/Paris", "2024-06-30T23:30:00+02:00", "quarter", "2024-04-01T00:00:00+02:00"},
+		{"year over a spring forward", "Europe/Paris", "2024-12-31T23:30:00+01:00", "year", "2024-01-01T00:00:00+01:00"},
+		// midnight is skipped on this day, postgres gives the first instant
+		// after the gap
+		{"day starting in a dst gap", "America/Sao_Paulo", "2018-11-04T12:00:00-02:00", "day", "2018-11-04T01:00:00-02:00"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			location, err := time.LoadLocation(test.location)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			value, err := time.Parse(time.RFC3339, test.t)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := time.Parse(time.RFC3339, test.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := truncateDate(value.In(location), test.unit)
+			if !got.Equal(want) {
+				t.Errorf("truncateDate(%s, %q) = %s, want %s", test.t, test.unit, got.Format(time.RFC3339), test.want)
+			}
+		})
+	}
+}
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		interval string
+		n        int
+		unit     string
+		ok       bool
+	}{
+		{"1 day", 1, "day", true},
+		{"2 weeks", 2, "week", true},
+		{"1 Month", 1, "month", true},
+		{"  3   months ", 3, "month", true},
+		{"1 quarter", 1, "quarter", true},
+		{"0 years", 0, "year", true},
+		{"", 0, "", false},
+		{"month", 0, "", false},
+		{"-1 day", 0, "", false},
+		{"1.5 days", 0, "", false},
+		{"1 hour", 0, "", false},
+		{"1 day 2 hours", 0, "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.interval, func(t *testing.T) {
+			n, unit, err := parseInterval(test.interval)
+			if (err == nil) != test.ok {
+				t.Fatalf("parseInterval(%q) error = %v, want ok %v", test.interval, err, test.ok)
+			}
+
+			if n != test.n || unit != test.unit {
+				t.Errorf("parseInterval(%q) = %d, %q, want %d, %q", test.interval, n, unit, test.n, test.unit)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// structured filters on the logs, shared by every endpoint that lists logs
+type LogFilters struct {
+	projectId     string
+	statuses      []string
+	types         []string
+	priorities    []int
+	createdFrom   *time.Time
+	createdTo     *time.Time
+	startedFrom   *time.Time
+	startedTo     *time.Time
+	completedFrom *time.Time
+	completedTo   *time.Time
+	updatedFrom   *time.Time
+	updatedTo     *time.Time
+	tags          []string
+	allTags       bool
+	blocked       *bool
+}
+
+// values of a repeatable query param, "a,b" is the same as "a&a=b"
+func queryValues(query url.Values, key string) []string {
+	values := []string{}
+	for _, value := range query[key] {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part != "" {
+				values = append(values, part)
+			}
+		}
+	}
+
+	return values
+}
+
+// parse a filter date, either RFC3339 or YYYY-MM-DD. A plain date is a day
+// in the location, used as an upper bound it covers the whole day
+func parseFilterTime(query url.Values, key string, upperBound bool, location *time.Location) (*time.Time, error) {
+	value := strings.TrimSpace(query.Get(key))
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		t = t.UTC()
+		return &t, nil
+	}
+
+	t, err := time.ParseInLocation(time.DateOnly, value, location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value %q", key, value)
+	}
+
+	if upperBound {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	t = t.UTC()
+	return &t, nil
+}
+
+// read the filters from the query string of a request, the values are
+// checked against the vocabulary
+func parseLogFilters(query url.Values, vocabulary Vocabulary) (LogFilters, error) {
+	var filters LogFilters
+	var err error
+
+	filters.projectId = strings.TrimSpace(query.Get("projectId"))
+	filters.statuses = queryValues(query, "status")
+	for _, status := range filters.statuses {
+		if !vocabulary.validateTaskStatus(status) {
+			return filters, fmt.Errorf("invalid status value %q", status)
+		}
+	}
+
+	filters.types = queryValues(query, "type")
+	for _, taskType := range filters.types {
+		if !vocabulary.validateTaskType(taskType) {
+			return filters, fmt.Errorf("invalid type value %q", taskType)
+		}
+	}
+
+	for _, value := range queryValues(query, "priority") {
+		priority, err := strconv.Atoi(value)
+		if err != nil || !vocabulary.validatePriority(priority) {
+			return filters, fmt.Errorf("invalid priority value %q", value)
+		}
+
+		filters.priorities = append(filters.priorities, priority)
+	}
+
+	filters.tags, err = normalizeTags(queryValues(query, "tag"))
+	if err != nil {
+		return filters, err
+	}
+
+	// logs with any of the tags by default, or with all of them
+	switch query.Get("tagMode") {
+	case "", "any":
+	case "all":
+		filters.allTags = true
+	default:
+		return filters, fmt.Errorf("invalid tagMode value %q", query.Get("tagMode"))
+	}
+
+	// logs that still wait on an unfinished blocker, or that don't
+	if value := query.Get("blocked"); value != "" {
+		blocked, err := strconv.ParseBool(value)
+		if err != nil {
+			return filters, fmt.Errorf("invalid blocked value %q", value)
+		}
+
+		filters.blocked = &blocked
+	}
+
+	ranges := []struct {
+		key        string
+		dest       **time.Time
+		upperBound bool
+	}{
+		{"createdFrom", &filters.createdFrom, false},
+		{"createdTo", &filters.createdTo, true},
+		{"startedFrom", &filters.startedFrom, false},
+		{"startedTo", &filters.startedTo, true},
+		{"completedFrom", &filters.completedFrom, false},
+		{"completedTo", &filters.completedTo, true},
+		{"updatedFrom", &filters.updatedFrom, false},
+		{"updatedTo", &filters.updatedTo, true},
+	}
+
+	for _, r := range ranges {
+		*r.dest, err = parseFilterTime(query, r.key, r.upperBound, time.UTC)
+		if err != nil {
+			return filters, err
+		}
+	}
+
+	return filters, nil
+}
+
+// where clause out of a list of conditions
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+
+	return "where " + strings.Join(conditions, " and ")
+}
+
+// placeholders $n, $n+1, ... for a list of values
+func placeholders(values []any, argIdx int) string {
+	list := make([]string, len(values))
+	for i := range values {
+		list[i] = fmt.Sprintf("$%d", argIdx+i)
+	}
+
+	return strings.Join(list, ", ")
+}
+
+// where conditions of the filters on the logs table, placeholders are
+// numbered from argIdx. The sql is the same for postgres and sqlite, like
+// the other queries built outside of the stores
+func (filters LogFilters) conditions(argIdx int) ([]string, []any) {
+	conditions := []string{}
+	args := []any{}
+
+	in := func(column string, values []any) {
+		if len(values) == 0 {
+			return
+		}
+
+		conditions = append(conditions, fmt.Sprintf("logs.%s in (%s)", column, placeholders(values, argIdx)))
+		args = append(args, values...)
+		argIdx += len(values)
+	}
+
+	compare := func(column string, operator string, value *time.Time) {
+		if value == nil {
+			return
+		}
+
+		conditions = append(conditions, fmt.Sprintf("logs.%s %s $%d", column, operator, argIdx))
+		args = append(args, *value)
+		argIdx++
+	}
+
+	statuses := []any{}
+	for _, status := range filters.statuses {
+		statuses = append(statuses, status)
+	}
+
+	types := []any{}
+	for _, taskType := range filters.types {
+		types = append(types, taskType)
+	}
+
+	priorities := []any{}
+	for _, priority := range filters.priorities {
+		priorities = append(priorities, priority)
+	}
+
+	if filters.projectId != "" {
+		conditions = append(conditions, fmt.Sprintf("logs.project_id = $%d", argIdx))
+		args = append(args, filters.projectId)
+		argIdx++
+	}
+
+	in("task_status", statuses)
+	in("task_type", types)
+	in("priority", priorities)
+	compare("created_at", ">=", filters.createdFrom)
+	compare("created_at", "<=", filters.createdTo)
+	compare("started_at", ">=", filters.startedFrom)
+	compare("started_at", "<=", filters.startedTo)
+	compare("completed_at", ">=", filters.completedFrom)
+	compare("completed_at", "<=", filters.completedTo)
+	compare("updated_at", ">=", filters.updatedFrom)
+	compare("updated_at", "<=", filters.updatedTo)
+
+	if len(filters.tags) > 0 {
+		tags := []any{}
+		for _, tag := range filters.tags {
+			tags = append(tags, tag)
+		}
+
+		condition := fmt.Sprintf("logs.log_id in (select log_id from log_tags where tag_name in (%s)", placeholders(tags, argIdx))
+		if filters.allTags {
+			condition += fmt.Sprintf(" group by log_id having count(*) = %d", len(tags))
+		}
+
+		conditions = append(conditions, condition+")")
+		args = append(args, tags...)
+		argIdx += len(tags)
+	}
+
+	// a blocker stops blocking once it is in a terminal status
+	if filters.blocked != nil {
+		condition := `exists (
+			select 1 from log_links
+			join logs blocker on blocker.log_id = log_links.blocker_log_id
+			where log_links.blocked_log_id = logs.log_id and blocker.task_status not in (` + terminalStatusesQuery + `)
+		)`
+		if !*filters.blocked {
+			condition = "not " + condition
+		}
+
+		conditions = append(conditions, condition)
+	}
+
+	return conditions, args
+}
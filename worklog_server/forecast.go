@@ -0,0 +1,336 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bounds of the forecast params
+const (
+	defaultForecastHistoryDays = 90
+	maxForecastHistoryDays     = 366
+	defaultForecastTrials      = 10000
+	maxForecastTrials          = 100000
+	maxForecastDays            = 3650
+	maxForecastItems           = 10000
+)
+
+// most days sampled by the trials of a forecast together, it bounds the
+// horizon of a trial so a forecast stays cheap whatever its params
+const maxForecastDraws = 20000000
+
+// confidence levels of a forecast, in percent
+var forecastConfidences = []int{50, 85, 95}
+
+// options of a forecast. Either items are forecast to a completion date or
+// the items done by date are counted, sampling the daily throughput of the
+// history days before today
+type ForecastOptions struct {
+	filters     LogFilters
+	location    *time.Location
+	items       *int
+	date        *time.Time
+	historyDays int
+	trials      int
+	today       time.Time
+}
+
+// days needed to finish the items, with the confidence they're enough. When
+// too many trials didn't finish within the horizon the level isn't reached,
+// days and date are nil
+type ForecastDate struct {
+	Confidence int     `json:"confidence"`
+	Reached    bool    `json:"reached"`
+	Days       *int    `json:"days"`
+	Date       *string `json:"date"`
+}
+
+// items finished by the date, with the confidence that at least that many are
+type ForecastItems struct {
+	Confidence int `json:"confidence"`
+	Items      int `json:"items"`
+}
+
+// read a positive int param, fallback when it is missing
+func parsePositiveInt(query url.Values, key string, fallback int, max int) (int, error) {
+	value := strings.TrimSpace(query.Get(key))
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > max {
+		return 0, fmt.Errorf("invalid %s value %q, expected a number between 1 and %d", key, value, max)
+	}
+
+	return n, nil
+}
+
+func parseForecastOptions(query url.Values, vocabulary Vocabulary) (ForecastOptions, error) {
+	var options ForecastOptions
+	var err error
+
+	options.filters, err = parseLogFilters(query, vocabulary)
+	if err != nil {
+		return options, err
+	}
+
+	options.location, err = parseTimezone(query)
+	if err != nil {
+		return options, err
+	}
+
+	options.today = truncateDate(time.Now().In(options.location), "day")
+	options.historyDays, err = parsePositiveInt(query, "historyDays", defaultForecastHistoryDays, maxForecastHistoryDays)
+	if err != nil {
+		return options, err
+	}
+
+	options.trials, err = parsePositiveInt(query, "trials", defaultForecastTrials, maxForecastTrials)
+	if err != nil {
+		return options, err
+	}
+
+	options.date, err = parseDay(query, "date", options.location)
+	if err != nil {
+		return options, err
+	}
+
+	if options.date != nil && !options.date.After(options.today) {
+		return options, fmt.Errorf("date must be after today")
+	}
+
+	if options.date != nil && options.date.After(options.today.AddDate(0, 0, maxForecastDays)) {
+		return options, fmt.Errorf("date can't be more than %d days away", maxForecastDays)
+	}
+
+	if query.Get("items") != "" {
+		if options.date != nil {
+			return options, fmt.Errorf("use either items or date")
+		}
+
+		items, err := parsePositiveInt(query, "items", 0, maxForecastItems)
+		if err != nil {
+			return options, err
+		}
+
+		options.items = &items
+	}
+
+	if options.date != nil && options.days()*options.trials > maxForecastDraws {
+		return options, fmt.Errorf("the date is too far for %d trials, use at most %d trials", options.trials, maxForecastDraws/options.days())
+	}
+
+	return options, nil
+}
+
+// days from today to the date of the forecast
+func (options ForecastOptions) days() int {
+	return int(math.Round(options.date.Sub(options.today).Hours() / 24))
+}
+
+// most days a trial runs before it is given up
+func (options ForecastOptions) horizon() int {
+	return min(maxForecastDays, maxForecastDraws/options.trials)
+}
+
+// completed logs of each history day, the days without any are zeros. The
+// history is scoped by the project, types, priorities and tags of the
+// filters, not by the statuses or dates meant for the open logs
+func forecastThroughput(store Store, options ForecastOptions) ([]int, error) {
+	countOptions := CompletedCountOptions{
+		LogFilters: LogFilters{
+			projectId:  options.filters.projectId,
+			types:      options.filters.types,
+			priorities: options.filters.priorities,
+			tags:       options.filters.tags,
+			allTags:    options.filters.allTags,
+		},
+		view:     "day",
+		from:     options.today.AddDate(0, 0, -options.historyDays).UTC(),
+		to:       options.today.Add(-time.Nanosecond).UTC(),
+		location: options.location,
+	}
+
+	rows, err := store.GetCompletedTaskCount(countOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	total, _ := completedCountSeries(rows, countOptions, Vocabulary{})
+	throughput := []int{}
+	for _, count := range total {
+		throughput = append(throughput, count.TaskCount)
+	}
+
+	return throughput, nil
+}
+
+// days each finished trial took to finish the items, sorted, and the count
+// of trials still running after the horizon
+func simulateDays(throughput []int, items int, trials int, horizon int) ([]float64, int) {
+	days := []float64{}
+	unfinished := 0
+	for range trials {
+		done := 0
+		day := 0
+		for done < items && day < horizon {
+			done += throughput[rand.IntN(len(throughput))]
+			day++
+		}
+
+		if done < items {
+			unfinished++
+			continue
+		}
+
+		days = append(days, float64(day))
+	}
+
+	sort.Float64s(days)
+	return days, unfinished
+}
+
+// completion of a confidence level. The unfinished trials sort after every
+// finished one, the level is reached when the ranks it interpolates between
+// are finished trials
+func forecastDate(days []float64, unfinished int, confidence int, today time.Time) ForecastDate {
+	forecast := ForecastDate{Confidence: confidence}
+	rank := float64(confidence) / 100 * float64(len(days)+unfinished-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if upper >= len(days) {
+		return forecast
+	}
+
+	n := int(math.Ceil(days[lower] + (days[upper]-days[lower])*(rank-float64(lower))))
+	date := today.AddDate(0, 0, n).Format(time.DateOnly)
+	forecast.Reached = true
+	forecast.Days = &n
+	forecast.Date = &date
+	return forecast
+}
+
+// items each trial finished in the days, sorted
+func simulateItems(throughput []int, days int, trials int) []float64 {
+	items := make([]float64, trials)
+	for trial := range items {
+		done := 0
+		for day := 0; day < days; day++ {
+			done += throughput[rand.IntN(len(throughput))]
+		}
+
+		items[trial] = float64(done)
+	}
+
+	sort.Float64s(items)
+	return items
+}
+
+func handleGetForecast(store Store, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vocabulary, ok := loadVocabulary(store, w)
+	if !ok {
+		return
+	}
+
+	options, err := parseForecastOptions(r.URL.Query(), vocabulary)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	if !validateProjectScope(store, w, options.filters) {
+		return
+	}
+
+	throughput, err := forecastThroughput(store, options)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while fetching the throughput"})
+		return
+	}
+
+	completed := 0
+	for _, count := range throughput {
+		completed += count
+	}
+
+	if completed == 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": "No logs were completed in the history days, there is nothing to forecast from"})
+		return
+	}
+
+	response := struct {
+		Today       string          `json:"today"`
+		Timezone    string          `json:"timezone"`
+		HistoryDays int             `json:"historyDays"`
+		Completed   int             `json:"completed"`
+		Trials      int             `json:"trials"`
+		Items       *int            `json:"items,omitempty"`
+		HorizonDays int             `json:"horizonDays,omitempty"`
+		Unfinished  *int            `json:"unfinishedTrials,omitempty"`
+		Date        string          `json:"date,omitempty"`
+		Dates       []ForecastDate  `json:"dates,omitempty"`
+		ItemCounts  []ForecastItems `json:"itemCounts,omitempty"`
+	}{
+		Today:       options.today.Format(time.DateOnly),
+		Timezone:    options.location.String(),
+		HistoryDays: options.historyDays,
+		Completed:   completed,
+		Trials:      options.trials,
+	}
+
+	if options.date != nil {
+		// at least that many items are done in confidence percent of the
+		// trials
+		items := simulateItems(throughput, options.days(), options.trials)
+		response.Date = options.date.Format(time.DateOnly)
+		for _, confidence := range forecastConfidences {
+			response.ItemCounts = append(response.ItemCounts, ForecastItems{
+				Confidence: confidence,
+				Items:      int(math.Floor(percentile(items, float64(100-confidence)))),
+			})
+		}
+	} else {
+		// the open logs matching the filters when items isn't given
+		items := 0
+		if options.items != nil {
+			items = *options.items
+		} else {
+			summary, err := store.GetStatusSummary(options.filters)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while counting the open logs"})
+				return
+			}
+
+			for _, row := range summary {
+				if !vocabulary.isTerminal(row.TaskStatus) {
+					items += row.StatusCount
+				}
+			}
+		}
+
+		response.Items = &items
+		response.HorizonDays = options.horizon()
+		days, unfinished := simulateDays(throughput, items, options.trials, options.horizon())
+		response.Unfinished = &unfinished
+		for _, confidence := range forecastConfidences {
+			response.Dates = append(response.Dates, forecastDate(days, unfinished, confidence, options.today))
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
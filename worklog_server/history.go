@@ -0,0 +1,455 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// actions recorded in the history of a log
+const (
+	logCreated = "create"
+	logUpdated = "update"
+	logDeleted = "delete"
+)
+
+// fields of a log tracked by the history, with the name used in the api
+var historyFields = []string{
+	"projectId",
+	"parentLogId",
+	"taskName",
+	"taskType",
+	"taskStatus",
+	"notes",
+	"startedAt",
+	"completedAt",
+	"dueAt",
+	"priority",
+	"tags",
+}
+
+// default and max number of events returned by /activity
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 500
+)
+
+// a create, update or delete of a log. The task name is the one the log had
+// at the time, so deleted logs can still be told apart
+type LogEvent struct {
+	EventId   int64       `json:"eventId"`
+	LogId     string      `json:"logId"`
+	Action    string      `json:"action"`
+	TaskName  string      `json:"taskName"`
+	ChangedAt time.Time   `json:"changedAt"`
+	Changes   []LogChange `json:"changes"`
+}
+
+// old and new value of a field, nil when the field had no value
+type LogChange struct {
+	Field    string  `json:"field"`
+	OldValue *string `json:"oldValue"`
+	NewValue *string `json:"newValue"`
+}
+
+// storage of the change history of the logs
+type HistoryStore interface {
+	GetActivity(filters ActivityFilters) ([]LogEvent, error)
+}
+
+// filters of the history feed, the newest events come first. Events with an
+// id below before are the next page
+type ActivityFilters struct {
+	logId   string
+	from    *time.Time
+	to      *time.Time
+	fields  []string
+	actions []string
+	before  int64
+	limit   int
+}
+
+// read the activity filters from the query string of a request
+func parseActivityFilters(query url.Values) (ActivityFilters, error) {
+	var filters ActivityFilters
+	var err error
+
+	if filters.from, err = parseFilterTime(query, "from", false, time.UTC); err != nil {
+		return filters, err
+	}
+
+	if filters.to, err = parseFilterTime(query, "to", true, time.UTC); err != nil {
+		return filters, err
+	}
+
+	filters.fields = queryValues(query, "field")
+	for _, field := range filters.fields {
+		if !isHistoryField(field) {
+			return filters, fmt.Errorf("invalid field value %q", field)
+		}
+	}
+
+	filters.actions = queryValues(query, "action")
+	for _, action := range filters.actions {
+		if action != logCreated && action != logUpdated && action != logDeleted {
+			return filters, fmt.Errorf("invalid action value %q", action)
+		}
+	}
+
+	if value := query.Get("before"); value != "" {
+		filters.before, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || filters.before < 1 {
+			return filters, fmt.Errorf("invalid before value %q", value)
+		}
+	}
+
+	filters.limit = defaultActivityLimit
+	if value := query.Get("limit"); value != "" {
+		filters.limit, err = strconv.Atoi(value)
+		if err != nil || filters.limit < 1 {
+			return filters, fmt.Errorf("invalid limit value %q", value)
+		}
+
+		filters.limit = min(filters.limit, maxActivityLimit)
+	}
+
+	return filters, nil
+}
+
+func isHistoryField(field string) bool {
+	for _, historyField := range historyFields {
+		if field == historyField {
+			return true
+		}
+	}
+
+	return false
+}
+
+// value of every tracked field of a log, in the order of historyFields
+func historyValues(workLog WorkLog) []*string {
+	text := func(value string) *string {
+		return &value
+	}
+
+	timeText := func(value *time.Time) *string {
+		if value == nil {
+			return nil
+		}
+
+		return text(value.UTC().Format(time.RFC3339Nano))
+	}
+
+	return []*string{
+		text(workLog.ProjectId),
+		workLog.ParentLogId,
+		text(workLog.TaskName),
+		text(workLog.TaskType),
+		text(workLog.TaskStatus),
+		text(workLog.Notes),
+		timeText(workLog.StartedAt),
+		timeText(workLog.CompletedAt),
+		timeText(workLog.DueAt),
+		text(strconv.Itoa(workLog.Priority)),
+		text(strings.Join(workLog.Tags, " ")),
+	}
+}
+
+// fields that differ between two versions of a log
+func diffWorkLogs(before WorkLog, after WorkLog) []LogChange {
+	changes := []LogChange{}
+	oldValues := historyValues(before)
+	newValues := historyValues(after)
+	for i, field := range historyFields {
+		oldValue, newValue := oldValues[i], newValues[i]
+		if oldValue == nil && newValue == nil {
+			continue
+		}
+
+		if oldValue != nil && newValue != nil && *oldValue == *newValue {
+			continue
+		}
+
+		changes = append(changes, LogChange{Field: field, OldValue: oldValue, NewValue: newValue})
+	}
+
+	return changes
+}
+
+// record a change of a log inside the transaction that made it. A create
+// has no before and a delete has no after
+func recordLogEvent(tx *sql.Tx, action string, before WorkLog, after WorkLog) error {
+	workLog := after
+	if action == logDeleted {
+		workLog = before
+	}
+
+	changes := diffWorkLogs(before, after)
+	if action == logUpdated && len(changes) == 0 {
+		return nil
+	}
+
+	for i := range changes {
+		switch action {
+		case logCreated:
+			changes[i].OldValue = nil
+		case logDeleted:
+			changes[i].NewValue = nil
+		}
+	}
+
+	var eventId int64
+	q := "insert into log_events (log_id, action, task_name) values ($1, $2, $3) returning event_id"
+	if err := tx.QueryRow(q, workLog.LogId, action, workLog.TaskName).Scan(&eventId); err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		q := "insert into log_changes (event_id, field, old_value, new_value) values ($1, $2, $3, $4)"
+		if _, err := tx.Exec(q, eventId, change.Field, change.OldValue, change.NewValue); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// record the deleted logs and the subtasks moved to another parent by the
+// delete
+func recordLogDeletes(tx *sql.Tx, deleted []WorkLog, moved []WorkLog) error {
+	for _, workLog := range deleted {
+		if err := recordLogEvent(tx, logDeleted, workLog, WorkLog{}); err != nil {
+			return err
+		}
+	}
+
+	for _, workLog := range moved {
+		after, err := getLogTx(tx, workLog.LogId)
+		if err != nil {
+			return err
+		}
+
+		if err := recordLogEvent(tx, logUpdated, workLog, after); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// logs selected by a query on workLogColumns, inside a transaction
+func queryLogsTx(tx *sql.Tx, q string, args ...any) ([]WorkLog, error) {
+	rows, err := tx.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	logs := []WorkLog{}
+	for rows.Next() {
+		workLog, err := scanWorkLog(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		logs = append(logs, workLog)
+	}
+
+	return logs, rows.Err()
+}
+
+// get a log inside a transaction
+func getLogTx(tx *sql.Tx, logId string) (WorkLog, error) {
+	return scanWorkLog(tx.QueryRow("select "+workLogColumns+" from logs where log_id = $1", logId))
+}
+
+// query of the events matching the filters
+func (filters ActivityFilters) eventsQuery() (string, []any) {
+	conditions := []string{}
+	args := []any{}
+	argIdx := 1
+
+	if filters.logId != "" {
+		conditions = append(conditions, fmt.Sprintf("log_id = $%d", argIdx))
+		args = append(args, filters.logId)
+		argIdx++
+	}
+
+	if filters.from != nil {
+		conditions = append(conditions, fmt.Sprintf("changed_at >= $%d", argIdx))
+		args = append(args, *filters.from)
+		argIdx++
+	}
+
+	if filters.to != nil {
+		conditions = append(conditions, fmt.Sprintf("changed_at <= $%d", argIdx))
+		args = append(args, *filters.to)
+		argIdx++
+	}
+
+	if len(filters.actions) > 0 {
+		actions := []any{}
+		for _, action := range filters.actions {
+			actions = append(actions, action)
+		}
+
+		conditions = append(conditions, fmt.Sprintf("action in (%s)", placeholders(actions, argIdx)))
+		args = append(args, actions...)
+		argIdx += len(actions)
+	}
+
+	if len(filters.fields) > 0 {
+		fields := []any{}
+		for _, field := range filters.fields {
+			fields = append(fields, field)
+		}
+
+		conditions = append(conditions, fmt.Sprintf("event_id in (select event_id from log_changes where field in (%s))", placeholders(fields, argIdx)))
+		args = append(args, fields...)
+		argIdx += len(fields)
+	}
+
+	if filters.before > 0 {
+		conditions = append(conditions, fmt.Sprintf("event_id < $%d", argIdx))
+		args = append(args, filters.before)
+		argIdx++
+	}
+
+	q := "select event_id, log_id, action, task_name, changed_at from log_events " + whereClause(conditions) + " order by event_id desc"
+	if filters.limit > 0 {
+		q += fmt.Sprintf(" limit %d", filters.limit)
+	}
+
+	return q, args
+}
+
+// query of the changes of the events, only the filtered fields when there
+// are any
+func (filters ActivityFilters) changesQuery(events []LogEvent) (string, []any) {
+	eventIds := []any{}
+	for _, event := range events {
+		eventIds = append(eventIds, event.EventId)
+	}
+
+	q := fmt.Sprintf("select event_id, field, old_value, new_value from log_changes where event_id in (%s)", placeholders(eventIds, 1))
+	args := eventIds
+	if len(filters.fields) > 0 {
+		fields := []any{}
+		for _, field := range filters.fields {
+			fields = append(fields, field)
+		}
+
+		q += fmt.Sprintf(" and field in (%s)", placeholders(fields, len(args)+1))
+		args = append(args, fields...)
+	}
+
+	return q + " order by event_id, field", args
+}
+
+func scanLogEvents(rows *sql.Rows) ([]LogEvent, error) {
+	defer rows.Close()
+	events := []LogEvent{}
+	for rows.Next() {
+		event := LogEvent{Changes: []LogChange{}}
+		if err := rows.Scan(&event.EventId, &event.LogId, &event.Action, &event.TaskName, &event.ChangedAt); err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// add the changes scanned from rows to their events
+func scanLogChanges(events []LogEvent, rows *sql.Rows) error {
+	defer rows.Close()
+	index := map[int64]int{}
+	for i, event := range events {
+		index[event.EventId] = i
+	}
+
+	for rows.Next() {
+		var eventId int64
+		var change LogChange
+		if err := rows.Scan(&eventId, &change.Field, &change.OldValue, &change.NewValue); err != nil {
+			return err
+		}
+
+		event := &events[index[eventId]]
+		event.Changes = append(event.Changes, change)
+	}
+
+	return rows.Err()
+}
+
+func handleGetLogHistory(store Store, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	logId := r.PathValue("logId")
+	if !uuidPattern.MatchString(logId) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "No records found"})
+		return
+	}
+
+	events, err := store.GetActivity(ActivityFilters{logId: logId})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while fetching the history"})
+		return
+	}
+
+	// logs created before the history existed have no events yet
+	if len(events) == 0 {
+		if _, err := store.GetLogById(logId); err != nil {
+			if err == ErrLogNotFound {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"message": "No records found"})
+				return
+			}
+
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while fetching the history"})
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		History []LogEvent `json:"history"`
+	}{History: events})
+}
+
+func handleGetActivity(store HistoryStore, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	filters, err := parseActivityFilters(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	events, err := store.GetActivity(filters)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while fetching the activity"})
+		return
+	}
+
+	// the next page starts below the oldest event of this one
+	var before int64
+	if len(events) == filters.limit {
+		before = events[len(events)-1].EventId
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Activity []LogEvent `json:"activity"`
+		Before   int64      `json:"before,omitempty"`
+	}{Activity: events, Before: before})
+}
@@ -0,0 +1,93 @@
+package main
+
+import "testing"
+
+func TestLogHistory(t *testing.T) {
+	store := newTestStore(t)
+	workLog := createTestLog(t, store, CreateLogInput{TaskName: "a", Notes: "first", Tags: []string{"api"}})
+
+	status := "progress"
+	if _, err := store.UpdateLog(workLog.LogId, UpdateLogInput{TaskStatus: status, Notes: "second"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// an update changing nothing isn't recorded
+	if _, err := store.UpdateLog(workLog.LogId, UpdateLogInput{TaskStatus: status}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.DeleteLog(workLog.LogId, false); err != nil {
+		t.Fatal(err)
+	}
+
+	text := func(value string) *string { return &value }
+	type change struct{ oldValue, newValue *string }
+	type event struct {
+		action  string
+		changes map[string]change
+	}
+
+	tests := []struct {
+		name    string
+		filters ActivityFilters
+		// the newest event first
+		want []event
+	}{
+		{
+			name:    "every field",
+			filters: ActivityFilters{logId: workLog.LogId, limit: defaultActivityLimit},
+			want: []event{
+				{logDeleted, map[string]change{"taskStatus": {text(status), nil}, "notes": {text("second"), nil}, "tags": {text("api"), nil}}},
+				{logUpdated, map[string]change{"taskStatus": {text("backlog"), text(status)}, "notes": {text("first"), text("second")}}},
+				{logCreated, map[string]change{"taskStatus": {nil, text("backlog")}, "notes": {nil, text("first")}, "tags": {nil, text("api")}}},
+			},
+		},
+		{
+			name:    "one field",
+			filters: ActivityFilters{logId: workLog.LogId, fields: []string{"notes"}, actions: []string{logUpdated}, limit: defaultActivityLimit},
+			want:    []event{{logUpdated, map[string]change{"notes": {text("first"), text("second")}}}},
+		},
+	}
+
+	sameValue := func(got *string, want *string) bool {
+		return (got == nil && want == nil) || (got != nil && want != nil && *got == *want)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events, err := store.GetActivity(test.filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(events) != len(test.want) {
+				t.Fatalf("GetActivity() = %d events, want %d", len(events), len(test.want))
+			}
+
+			for i, event := range events {
+				want := test.want[i]
+				if event.Action != want.action || event.TaskName != "a" {
+					t.Errorf("event %d = %s of %q, want %s of %q", i, event.Action, event.TaskName, want.action, "a")
+				}
+
+				changes := map[string]LogChange{}
+				for _, logChange := range event.Changes {
+					changes[logChange.Field] = logChange
+				}
+
+				// the other fields only have to be there on a create or a
+				// delete, the wanted ones must match
+				for field, wantChange := range want.changes {
+					got, ok := changes[field]
+					if !ok || !sameValue(got.OldValue, wantChange.oldValue) || !sameValue(got.NewValue, wantChange.newValue) {
+						t.Errorf("event %d change of %s = %+v, want %+v", i, field, got, wantChange)
+					}
+				}
+
+				if event.Action == logUpdated && len(changes) != len(want.changes) {
+					t.Errorf("event %d has %d changes, want %d", i, len(changes), len(want.changes))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stale thresholds of the highest and lowest priorities, the ones in between
+// are interpolated on the priority value
+const (
+	highestPriorityStaleAfter = 24 * time.Hour
+	lowestPriorityStaleAfter  = 14 * 24 * time.Hour
+)
+
+// an open log with the time it entered its current status
+type OpenLog struct {
+	WorkLog
+	StatusSince time.Time
+}
+
+// storage of the data behind the insights
+type InsightStore interface {
+	GetOpenLogs(filters LogFilters) ([]OpenLog, error)
+}
+
+// logs matching the filters that aren't in a terminal status, with their
+// last transition
+func openLogsQuery(filters LogFilters) (string, []any) {
+	conditions, args := filters.conditions(1)
+	conditions = append(conditions, "logs.task_status not in ("+terminalStatusesQuery+")")
+	q := `
+		with last_transitions as (
+			select transitions.log_id as transition_log_id, transitions.transitioned_at
+			from log_status_transitions transitions
+			where not exists (
+				select 1 from log_status_transitions later
+				where later.log_id = transitions.log_id
+					and (later.transitioned_at > transitions.transitioned_at
+						or (later.transitioned_at = transitions.transitioned_at and later.transition_id > transitions.transition_id))
+			)
+		)
+		select ` + workLogColumns + `, last_transitions.transitioned_at
+		from logs
+		left join last_transitions on last_transitions.transition_log_id = logs.log_id
+		` + whereClause(conditions) + `
+		order by logs.updated_at, logs.log_id`
+
+	return q, args
+}
+
+// scan the open logs, the logs without transitions are in their status since
+// they were created
+func scanOpenLogs(rows *sql.Rows) ([]OpenLog, error) {
+	defer rows.Close()
+	openLogs := []OpenLog{}
+	for rows.Next() {
+		var statusSince sql.NullTime
+		workLog, err := scanWorkLog(rows, &statusSince)
+		if err != nil {
+			return nil, err
+		}
+
+		openLog := OpenLog{WorkLog: workLog, StatusSince: workLog.CreatedAt}
+		if statusSince.Valid {
+			openLog.StatusSince = statusSince.Time
+		}
+
+		openLogs = append(openLogs, openLog)
+	}
+
+	return openLogs, rows.Err()
+}
+
+// how long a log can sit before it is stale. A status threshold wins over
+// the one of the priority
+type StaleThresholds struct {
+	priorities map[int]time.Duration
+	statuses   map[string]time.Duration
+}
+
+// threshold of a log, and whether it comes from its status
+func (thresholds StaleThresholds) of(workLog WorkLog) (time.Duration, bool) {
+	if threshold, ok := thresholds.statuses[workLog.TaskStatus]; ok {
+		return threshold, true
+	}
+
+	return thresholds.priorities[workLog.Priority], false
+}
+
+// default threshold of a priority, from a day for the highest one to two
+// weeks for the lowest one. Priorities out of the vocabulary get the closest
+// bound
+func defaultStaleThreshold(priority int, vocabulary Vocabulary) time.Duration {
+	lowest := vocabulary.lowestPriority()
+	highest := vocabulary.highestPriority()
+	if highest == lowest {
+		return highestPriorityStaleAfter
+	}
+
+	share := min(max(float64(priority-lowest)/float64(highest-lowest), 0), 1)
+	threshold := lowestPriorityStaleAfter - time.Duration(share*float64(lowestPriorityStaleAfter-highestPriorityStaleAfter))
+	return threshold.Round(time.Hour)
+}
+
+// read a threshold, either an interval like "3 days" or a duration like
+// "36h"
+func parseStaleThreshold(key string, value string) (time.Duration, error) {
+	if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+		return duration, nil
+	}
+
+	n, unit, err := parseInterval(value)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("invalid %s value %q, expected an interval like \"3 days\" or a duration like \"36h\"", key, value)
+	}
+
+	now := time.Now()
+	return addInterval(now, n, unit).Sub(now), nil
+}
+
+// read the thresholds of a request. priority.<value> and status.<name>
+// override the default of a priority and set the one of a status
+func parseStaleThresholds(query url.Values, vocabulary Vocabulary) (StaleThresholds, error) {
+	thresholds := StaleThresholds{priorities: map[int]time.Duration{}, statuses: map[string]time.Duration{}}
+	for _, priority := range vocabulary.Priorities {
+		thresholds.priorities[priority.Value] = defaultStaleThreshold(priority.Value, vocabulary)
+	}
+
+	for key := range query {
+		value := strings.TrimSpace(query.Get(key))
+		switch {
+		case strings.HasPrefix(key, "priority."):
+			priority, err := strconv.Atoi(strings.TrimPrefix(key, "priority."))
+			if err != nil || !vocabulary.validatePriority(priority) {
+				return thresholds, fmt.Errorf("unknown priority in %s", key)
+			}
+
+			threshold, err := parseStaleThreshold(key, value)
+			if err != nil {
+				return thresholds, err
+			}
+
+			thresholds.priorities[priority] = threshold
+		case strings.HasPrefix(key, "status."):
+			status := strings.TrimPrefix(key, "status.")
+			if !vocabulary.validateTaskStatus(status) || vocabulary.isTerminal(status) {
+				return thresholds, fmt.Errorf("unknown or terminal status in %s", key)
+			}
+
+			threshold, err := parseStaleThreshold(key, value)
+			if err != nil {
+				return thresholds, err
+			}
+
+			thresholds.statuses[status] = threshold
+		}
+	}
+
+	return thresholds, nil
+}
+
+// thresholds of a response in seconds, keyed by priority value and status
+type StaleThresholdSeconds struct {
+	Priorities map[string]int64 `json:"priorities"`
+	Statuses   map[string]int64 `json:"statuses"`
+}
+
+// an open log past its threshold. Reason is "updated" when it hasn't been
+// touched for too long and "status" when it has been in its status too long
+// while being edited, age is the one of the reason
+type StaleLog struct {
+	LogId             string    `json:"logId"`
+	TaskName          string    `json:"taskName"`
+	TaskType          string    `json:"taskType"`
+	TaskStatus        string    `json:"taskStatus"`
+	Priority          int       `json:"priority"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+	StatusSince       time.Time `json:"statusSince"`
+	AgeSeconds        int64     `json:"ageSeconds"`
+	ThresholdSeconds  int64     `json:"thresholdSeconds"`
+	StatusAgeSeconds  int64     `json:"statusAgeSeconds"`
+	UpdatedAgeSeconds int64     `json:"updatedAgeSeconds"`
+	Reason            string    `json:"reason"`
+	Message           string    `json:"message"`
+}
+
+// readable duration in days, or hours under two days
+func ageText(age time.Duration) string {
+	if age < 48*time.Hour {
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	}
+
+	return fmt.Sprintf("%d days", int(age.Hours()/24))
+}
+
+// the open logs past their threshold, the most overdue first
+func staleLogs(openLogs []OpenLog, thresholds StaleThresholds, vocabulary Vocabulary, now time.Time) []StaleLog {
+	stale := []StaleLog{}
+	for _, openLog := range openLogs {
+		threshold, fromStatus := thresholds.of(openLog.WorkLog)
+		if threshold == 0 {
+			threshold = defaultStaleThreshold(openLog.Priority, vocabulary)
+		}
+
+		statusAge := now.Sub(openLog.StatusSince)
+		updatedAge := now.Sub(openLog.UpdatedAt)
+		if statusAge <= threshold && updatedAge <= threshold {
+			continue
+		}
+
+		// a log left untouched is reported as such, otherwise it is stuck in
+		// its status while being edited
+		reason, age := "status", statusAge
+		if updatedAge > threshold {
+			reason, age = "updated", updatedAge
+		}
+
+		limit := fmt.Sprintf("the %s limit of priority %s", ageText(threshold), vocabulary.label("priority", strconv.Itoa(openLog.Priority)))
+		if fromStatus {
+			limit = fmt.Sprintf("the %s limit of %s", ageText(threshold), openLog.TaskStatus)
+		}
+
+		message := fmt.Sprintf("In %s for %s, over %s", openLog.TaskStatus, ageText(age), limit)
+		if reason == "updated" {
+			message = fmt.Sprintf("Not updated for %s, over %s", ageText(age), limit)
+		}
+
+		stale = append(stale, StaleLog{
+			LogId:             openLog.LogId,
+			TaskName:          openLog.TaskName,
+			TaskType:          openLog.TaskType,
+			TaskStatus:        openLog.TaskStatus,
+			Priority:          openLog.Priority,
+			UpdatedAt:         openLog.UpdatedAt,
+			StatusSince:       openLog.StatusSince,
+			AgeSeconds:        int64(age.Seconds()),
+			ThresholdSeconds:  int64(threshold.Seconds()),
+			StatusAgeSeconds:  int64(statusAge.Seconds()),
+			UpdatedAgeSeconds: int64(updatedAge.Seconds()),
+			Reason:            reason,
+			Message:           message,
+		})
+	}
+
+	sort.SliceStable(stale, func(i, j int) bool {
+		return float64(stale[i].AgeSeconds)/float64(stale[i].ThresholdSeconds) > float64(stale[j].AgeSeconds)/float64(stale[j].ThresholdSeconds)
+	})
+
+	return stale
+}
+
+func handleGetStaleLogs(store Store, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vocabulary, ok := loadVocabulary(store, w)
+	if !ok {
+		return
+	}
+
+	filters, err := parseLogFilters(r.URL.Query(), vocabulary)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	if !validateProjectScope(store, w, filters) {
+		return
+	}
+
+	thresholds, err := parseStaleThresholds(r.URL.Query(), vocabulary)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	openLogs, err := store.GetOpenLogs(filters)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while fetching open logs"})
+		return
+	}
+
+	// thresholds in seconds keyed by priority value and status
+	thresholdSeconds := StaleThresholdSeconds{Priorities: map[string]int64{}, Statuses: map[string]int64{}}
+	for priority, threshold := range thresholds.priorities {
+		thresholdSeconds.Priorities[strconv.Itoa(priority)] = int64(threshold.Seconds())
+	}
+
+	for status, threshold := range thresholds.statuses {
+		thresholdSeconds.Statuses[status] = int64(threshold.Seconds())
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Thresholds StaleThresholdSeconds `json:"thresholds"`
+		OpenCount  int                   `json:"openCount"`
+		Stale      []StaleLog            `json:"stale"`
+	}{
+		Thresholds: thresholdSeconds,
+		OpenCount:  len(openLogs),
+		Stale:      staleLogs(openLogs, thresholds, vocabulary, time.Now()),
+	})
+}
@@ -0,0 +1,783 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// longest iteration, in days
+const maxIterationDays = 366
+
+// errors returned by the iteration stores
+var (
+	ErrIterationNotFound      = errors.New("no iteration found with this iteration id")
+	ErrDuplicateIterationName = errors.New("iteration name already exists in the project")
+	ErrIterationLogNotFound   = errors.New("the log isn't in the iteration")
+	ErrIterationLogProject    = errors.New("the log isn't in the project of the iteration")
+)
+
+// a date bounded iteration of a project, StartsOn and EndsOn are YYYY-MM-DD
+// days, both included
+type Iteration struct {
+	IterationId string    `json:"iterationId"`
+	ProjectId   string    `json:"projectId"`
+	Name        string    `json:"name"`
+	StartsOn    string    `json:"startsOn"`
+	EndsOn      string    `json:"endsOn"`
+	LogCount    int       `json:"logCount"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// fields to create or update an iteration, empty fields of an update are
+// left untouched
+type IterationInput struct {
+	ProjectId string
+	Name      string
+	StartsOn  string
+	EndsOn    string
+}
+
+// a log planned in an iteration, a nil estimate weights 0 in the burndown
+type IterationLog struct {
+	LogId       string     `json:"logId"`
+	TaskName    string     `json:"taskName"`
+	TaskType    string     `json:"taskType"`
+	TaskStatus  string     `json:"taskStatus"`
+	Priority    int        `json:"priority"`
+	CompletedAt *time.Time `json:"completedAt"`
+	Estimate    *float64   `json:"estimate"`
+	AddedAt     time.Time  `json:"addedAt"`
+}
+
+// storage of the iterations and their logs
+type IterationStore interface {
+	CreateIteration(input IterationInput) (Iteration, error)
+	GetIteration(iterationId string) (Iteration, error)
+	GetIterations(projectId string) ([]Iteration, error)
+	UpdateIteration(iterationId string, input IterationInput) (Iteration, error)
+	DeleteIteration(iterationId string) error
+	AddIterationLog(iterationId string, logId string, estimate *float64) error
+	RemoveIterationLog(iterationId string, logId string) error
+	GetIterationLogs(iterationId string) ([]IterationLog, error)
+	GetIterationTransitions(iterationId string) ([]StatusTransition, error)
+}
+
+// columns selected for an Iteration, in the order scanIteration expects them
+const iterationColumns = `iteration_id,
+	project_id,
+	name,
+	starts_on,
+	ends_on,
+	(select count(*) from iteration_logs where iteration_logs.iteration_id = iterations.iteration_id) as log_count,
+	created_at,
+	updated_at`
+
+func scanIteration(row rowScanner) (Iteration, error) {
+	var iteration Iteration
+	var startsOn, endsOn time.Time
+	err := row.Scan(
+		&iteration.IterationId,
+		&iteration.ProjectId,
+		&iteration.Name,
+		&startsOn,
+		&endsOn,
+		&iteration.LogCount,
+		&iteration.CreatedAt,
+		&iteration.UpdatedAt,
+	)
+
+	iteration.StartsOn = startsOn.Format(time.DateOnly)
+	iteration.EndsOn = endsOn.Format(time.DateOnly)
+	return iteration, err
+}
+
+// check if a name is taken by another iteration of the project
+func iterationNameExists(db *sql.DB, projectId string, name string, iterationId string) (bool, error) {
+	q := "select 1 from iterations where project_id = $1 and name = $2 and cast(iteration_id as text) <> $3"
+	var exists int
+	err := db.QueryRow(q, projectId, name, iterationId).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// create an iteration in a project that exists
+func createIteration(db *sql.DB, input IterationInput) (Iteration, error) {
+	exists, err := iterationNameExists(db, input.ProjectId, input.Name, "")
+	if err != nil {
+		return Iteration{}, err
+	}
+
+	if exists {
+		return Iteration{}, ErrDuplicateIterationName
+	}
+
+	q := "insert into iterations (project_id, name, starts_on, ends_on) values ($1, $2, $3, $4) returning iteration_id"
+	var iterationId string
+	if err := db.QueryRow(q, input.ProjectId, input.Name, input.StartsOn, input.EndsOn).Scan(&iterationId); err != nil {
+		return Iteration{}, err
+	}
+
+	return getIteration(db, iterationId)
+}
+
+func getIteration(db *sql.DB, iterationId string) (Iteration, error) {
+	if !uuidPattern.MatchString(iterationId) {
+		return Iteration{}, ErrIterationNotFound
+	}
+
+	q := "select " + iterationColumns + " from iterations where iteration_id = $1"
+	iteration, err := scanIteration(db.QueryRow(q, iterationId))
+	if err == sql.ErrNoRows {
+		return iteration, ErrIterationNotFound
+	}
+
+	return iteration, err
+}
+
+// iterations of a project, or of every project when projectId is empty,
+// the latest first
+func getIterations(db *sql.DB, projectId string) ([]Iteration, error) {
+	q := "select " + iterationColumns + " from iterations"
+	args := []any{}
+	if projectId != "" {
+		q += " where project_id = $1"
+		args = append(args, projectId)
+	}
+
+	rows, err := db.Query(q+" order by starts_on desc, name", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	iterations := []Iteration{}
+	for rows.Next() {
+		iteration, err := scanIteration(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		iterations = append(iterations, iteration)
+	}
+
+	return iterations, rows.Err()
+}
+
+// update the non empty fields of an iteration, now is the sql of the
+// current time of the database
+func updateIteration(db *sql.DB, iterationId string, input IterationInput, now string) (Iteration, error) {
+	iteration, err := getIteration(db, iterationId)
+	if err != nil {
+		return Iteration{}, err
+	}
+
+	if input.Name != "" {
+		exists, err := iterationNameExists(db, iteration.ProjectId, input.Name, iterationId)
+		if err != nil {
+			return Iteration{}, err
+		}
+
+		if exists {
+			return Iteration{}, ErrDuplicateIterationName
+		}
+	}
+
+	fields := []string{"updated_at = " + now}
+	args := []any{}
+	set := func(column string, value string) {
+		if value != "" {
+			args = append(args, value)
+			fields = append(fields, fmt.Sprintf("%s = $%d", column, len(args)))
+		}
+	}
+
+	set("name", input.Name)
+	set("starts_on", input.StartsOn)
+	set("ends_on", input.EndsOn)
+	args = append(args, iterationId)
+	q := fmt.Sprintf("update iterations set %s where iteration_id = $%d", strings.Join(fields, ", "), len(args))
+	if _, err := db.Exec(q, args...); err != nil {
+		return Iteration{}, err
+	}
+
+	return getIteration(db, iterationId)
+}
+
+func deleteIteration(db *sql.DB, iterationId string) error {
+	if !uuidPattern.MatchString(iterationId) {
+		return ErrIterationNotFound
+	}
+
+	result, err := db.Exec("delete from iterations where iteration_id = $1", iterationId)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return ErrIterationNotFound
+	}
+
+	return nil
+}
+
+// add a log of the project to an iteration, adding it again updates its
+// estimate
+func addIterationLog(db *sql.DB, iterationId string, logId string, estimate *float64) error {
+	iteration, err := getIteration(db, iterationId)
+	if err != nil {
+		return err
+	}
+
+	if !uuidPattern.MatchString(logId) {
+		return ErrLogNotFound
+	}
+
+	var projectId string
+	err = db.QueryRow("select project_id from logs where log_id = $1", logId).Scan(&projectId)
+	if err == sql.ErrNoRows {
+		return ErrLogNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if projectId != iteration.ProjectId {
+		return ErrIterationLogProject
+	}
+
+	q := `insert into iteration_logs (iteration_id, log_id, estimate) values ($1, $2, $3)
+		on conflict (iteration_id, log_id) do update set estimate = excluded.estimate`
+	_, err = db.Exec(q, iterationId, logId, estimate)
+	return err
+}
+
+func removeIterationLog(db *sql.DB, iterationId string, logId string) error {
+	if _, err := getIteration(db, iterationId); err != nil {
+		return err
+	}
+
+	if !uuidPattern.MatchString(logId) {
+		return ErrIterationLogNotFound
+	}
+
+	result, err := db.Exec("delete from iteration_logs where iteration_id = $1 and log_id = $2", iterationId, logId)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return ErrIterationLogNotFound
+	}
+
+	return nil
+}
+
+func getIterationLogs(db *sql.DB, iterationId string) ([]IterationLog, error) {
+	if _, err := getIteration(db, iterationId); err != nil {
+		return nil, err
+	}
+
+	q := `
+		select
+			logs.log_id,
+			logs.task_name,
+			logs.task_type,
+			logs.task_status,
+			logs.priority,
+			logs.completed_at,
+			iteration_logs.estimate,
+			iteration_logs.added_at
+		from iteration_logs
+		join logs on logs.log_id = iteration_logs.log_id
+		where iteration_logs.iteration_id = $1
+		order by iteration_logs.added_at, logs.log_id`
+
+	rows, err := db.Query(q, iterationId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	iterationLogs := []IterationLog{}
+	for rows.Next() {
+		var iterationLog IterationLog
+		var completedAt sql.NullTime
+		var estimate sql.NullFloat64
+		err := rows.Scan(
+			&iterationLog.LogId,
+			&iterationLog.TaskName,
+			&iterationLog.TaskType,
+			&iterationLog.TaskStatus,
+			&iterationLog.Priority,
+			&completedAt,
+			&estimate,
+			&iterationLog.AddedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if completedAt.Valid {
+			iterationLog.CompletedAt = &completedAt.Time
+		}
+
+		if estimate.Valid {
+			iterationLog.Estimate = &estimate.Float64
+		}
+
+		iterationLogs = append(iterationLogs, iterationLog)
+	}
+
+	return iterationLogs, rows.Err()
+}
+
+// status transitions of the logs of an iteration, ordered by log and time
+func getIterationTransitions(db *sql.DB, iterationId string) ([]StatusTransition, error) {
+	q := `
+		select
+			log_status_transitions.log_id,
+			log_status_transitions.from_status,
+			log_status_transitions.to_status,
+			log_status_transitions.transitioned_at
+		from log_status_transitions
+		join iteration_logs on iteration_logs.log_id = log_status_transitions.log_id
+		where iteration_logs.iteration_id = $1
+		order by log_status_transitions.log_id, log_status_transitions.transitioned_at, log_status_transitions.transition_id`
+
+	rows, err := db.Query(q, iterationId)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanStatusTransitions(rows)
+}
+
+// remaining and completed weight of an iteration at the end of a day, nil
+// for the days still to come. Ideal is the remaining weight of a steady
+// burndown
+type BurndownDay struct {
+	Date      string   `json:"date"`
+	Remaining *float64 `json:"remaining"`
+	Completed *float64 `json:"completed"`
+	Ideal     float64  `json:"ideal"`
+}
+
+// weight of a log in the burndown
+func iterationLogWeight(iterationLog IterationLog, weight string) float64 {
+	switch weight {
+	case "priority":
+		return float64(iterationLog.Priority)
+	case "estimate":
+		if iterationLog.Estimate == nil {
+			return 0
+		}
+
+		return *iterationLog.Estimate
+	}
+
+	return 1
+}
+
+// remaining and completed weight at the end of every day of the iteration.
+// A log is completed at a time when its last status by then is terminal,
+// the logs without transitions fall back on completedAt
+func iterationBurndown(iteration Iteration, iterationLogs []IterationLog, transitions []StatusTransition, weight string, location *time.Location, vocabulary Vocabulary, now time.Time) []BurndownDay {
+	transitionsOf := map[string][]StatusTransition{}
+	for _, transition := range transitions {
+		transitionsOf[transition.LogId] = append(transitionsOf[transition.LogId], transition)
+	}
+
+	completedAt := func(iterationLog IterationLog, at time.Time) bool {
+		logTransitions := transitionsOf[iterationLog.LogId]
+		if len(logTransitions) == 0 {
+			return iterationLog.CompletedAt != nil && !iterationLog.CompletedAt.After(at)
+		}
+
+		status := ""
+		for _, transition := range logTransitions {
+			if transition.TransitionedAt.After(at) {
+				break
+			}
+
+			status = transition.ToStatus
+		}
+
+		return vocabulary.isTerminal(status)
+	}
+
+	total := 0.0
+	for _, iterationLog := range iterationLogs {
+		total += iterationLogWeight(iterationLog, weight)
+	}
+
+	startsOn, _ := time.ParseInLocation(time.DateOnly, iteration.StartsOn, location)
+	endsOn, _ := time.ParseInLocation(time.DateOnly, iteration.EndsOn, location)
+	days := int(endsOn.Sub(startsOn).Hours()/24+0.5) + 1
+
+	burndown := []BurndownDay{}
+	for i := 0; i < days; i++ {
+		day := startsOn.AddDate(0, 0, i)
+		ideal := 0.0
+		if days > 1 {
+			ideal = total * float64(days-1-i) / float64(days-1)
+		}
+
+		row := BurndownDay{Date: day.Format(time.DateOnly), Ideal: ideal}
+		if !day.After(now) {
+			end := day.AddDate(0, 0, 1)
+			if end.After(now) {
+				end = now
+			}
+
+			completed := 0.0
+			for _, iterationLog := range iterationLogs {
+				if completedAt(iterationLog, end) {
+					completed += iterationLogWeight(iterationLog, weight)
+				}
+			}
+
+			remaining := total - completed
+			row.Remaining = &remaining
+			row.Completed = &completed
+		}
+
+		burndown = append(burndown, row)
+	}
+
+	return burndown
+}
+
+// check the days of an iteration, they're in time.DateOnly
+func validateIterationDays(startsOn string, endsOn string) error {
+	start, err := time.Parse(time.DateOnly, startsOn)
+	if err != nil {
+		return fmt.Errorf("invalid startsOn value %q, expected YYYY-MM-DD", startsOn)
+	}
+
+	end, err := time.Parse(time.DateOnly, endsOn)
+	if err != nil {
+		return fmt.Errorf("invalid endsOn value %q, expected YYYY-MM-DD", endsOn)
+	}
+
+	if end.Before(start) {
+		return fmt.Errorf("endsOn can't be before startsOn")
+	}
+
+	if end.After(start.AddDate(0, 0, maxIterationDays-1)) {
+		return fmt.Errorf("an iteration can't be longer than %d days", maxIterationDays)
+	}
+
+	return nil
+}
+
+// write the error response of an iteration store error
+func writeIterationError(w http.ResponseWriter, err error) {
+	switch err {
+	case ErrIterationNotFound, ErrIterationLogNotFound, ErrLogNotFound, ErrProjectNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case ErrDuplicateIterationName:
+		w.WriteHeader(http.StatusConflict)
+	case ErrIterationLogProject:
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+}
+
+func handleGetIterations(store Store, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	projectId := r.URL.Query().Get("projectId")
+	if projectId != "" {
+		if _, err := store.GetProject(projectId); err != nil {
+			writeIterationError(w, err)
+			return
+		}
+	}
+
+	iterations, err := store.GetIterations(projectId)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while fetching iterations"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Iterations []Iteration `json:"iterations"`
+	}{Iterations: iterations})
+}
+
+func handleGetIteration(store Store, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	iterationId := r.PathValue("iterationId")
+	iteration, err := store.GetIteration(iterationId)
+	if err != nil {
+		writeIterationError(w, err)
+		return
+	}
+
+	iterationLogs, err := store.GetIterationLogs(iterationId)
+	if err != nil {
+		writeIterationError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Message   string         `json:"message"`
+		Iteration Iteration      `json:"iteration"`
+		Logs      []IterationLog `json:"logs"`
+	}{Message: "Ok", Iteration: iteration, Logs: iterationLogs})
+}
+
+func handleCreateIteration(store Store, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var body struct {
+		ProjectId string `json:"projectId"`
+		Name      string `json:"name"`
+		StartsOn  string `json:"startsOn"`
+		EndsOn    string `json:"endsOn"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	input := IterationInput{ProjectId: body.ProjectId, Name: strings.TrimSpace(body.Name), StartsOn: body.StartsOn, EndsOn: body.EndsOn}
+	if input.Name == "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Iteration name is required"})
+		return
+	}
+
+	if err := validateIterationDays(input.StartsOn, input.EndsOn); err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	iteration, err := store.CreateIteration(input)
+	if err != nil {
+		writeIterationError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		Message   string    `json:"message"`
+		Iteration Iteration `json:"iteration"`
+	}{Message: "Iteration created successfully", Iteration: iteration})
+}
+
+func handleUpdateIteration(store Store, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var body struct {
+		Name     string `json:"name"`
+		StartsOn string `json:"startsOn"`
+		EndsOn   string `json:"endsOn"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	input := IterationInput{Name: strings.TrimSpace(body.Name), StartsOn: body.StartsOn, EndsOn: body.EndsOn}
+	if input.Name == "" && input.StartsOn == "" && input.EndsOn == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "No valid fields to update"})
+		return
+	}
+
+	iteration, err := store.GetIteration(r.PathValue("iterationId"))
+	if err != nil {
+		writeIterationError(w, err)
+		return
+	}
+
+	// the days are checked together with the ones left untouched
+	startsOn, endsOn := iteration.StartsOn, iteration.EndsOn
+	if input.StartsOn != "" {
+		startsOn = input.StartsOn
+	}
+
+	if input.EndsOn != "" {
+		endsOn = input.EndsOn
+	}
+
+	if err := validateIterationDays(startsOn, endsOn); err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	iteration, err = store.UpdateIteration(iteration.IterationId, input)
+	if err != nil {
+		writeIterationError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Message   string    `json:"message"`
+		Iteration Iteration `json:"iteration"`
+	}{Message: "Iteration updated successfully", Iteration: iteration})
+}
+
+func handleDeleteIteration(store Store, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := store.DeleteIteration(r.PathValue("iterationId")); err != nil {
+		writeIterationError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Successfully deleted the iteration"})
+}
+
+func handleAddIterationLog(store Store, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var body struct {
+		LogId    string   `json:"logId"`
+		Estimate *float64 `json:"estimate"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	if body.LogId == "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Log id is required"})
+		return
+	}
+
+	if body.Estimate != nil && *body.Estimate < 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Estimate can't be negative"})
+		return
+	}
+
+	if err := store.AddIterationLog(r.PathValue("iterationId"), body.LogId, body.Estimate); err != nil {
+		writeIterationError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Log added to the iteration"})
+}
+
+func handleRemoveIterationLog(store Store, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := store.RemoveIterationLog(r.PathValue("iterationId"), r.PathValue("logId")); err != nil {
+		writeIterationError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Log removed from the iteration"})
+}
+
+func handleGetIterationBurndown(store Store, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vocabulary, ok := loadVocabulary(store, w)
+	if !ok {
+		return
+	}
+
+	weight := r.URL.Query().Get("weight")
+	switch weight {
+	case "":
+		weight = "count"
+	case "count", "priority", "estimate":
+	default:
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("invalid weight value %q, expected count, priority or estimate", weight)})
+		return
+	}
+
+	location, err := parseTimezone(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	iterationId := r.PathValue("iterationId")
+	iteration, err := store.GetIteration(iterationId)
+	if err != nil {
+		writeIterationError(w, err)
+		return
+	}
+
+	iterationLogs, err := store.GetIterationLogs(iterationId)
+	if err != nil {
+		writeIterationError(w, err)
+		return
+	}
+
+	transitions, err := store.GetIterationTransitions(iterationId)
+	if err != nil {
+		writeIterationError(w, err)
+		return
+	}
+
+	// logs without an estimate weight nothing, they're counted apart
+	total := 0.0
+	var unestimated *int
+	if weight == "estimate" {
+		unestimated = new(int)
+	}
+
+	for _, iterationLog := range iterationLogs {
+		total += iterationLogWeight(iterationLog, weight)
+		if unestimated != nil && iterationLog.Estimate == nil {
+			*unestimated++
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Iteration   Iteration     `json:"iteration"`
+		Weight      string        `json:"weight"`
+		Timezone    string        `json:"timezone"`
+		Total       float64       `json:"total"`
+		Unestimated *int          `json:"unestimated,omitempty"`
+		Days        []BurndownDay `json:"days"`
+	}{
+		Iteration:   iteration,
+		Weight:      weight,
+		Timezone:    location.String(),
+		Total:       total,
+		Unestimated: unestimated,
+		Days:        iterationBurndown(iteration, iterationLogs, transitions, weight, location, vocabulary, time.Now()),
+	})
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestIterationBurndown(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vocabulary := Vocabulary{TaskStatuses: []VocabularyTerm{
+		{Name: "backlog", Label: "Backlog", Position: 1},
+		{Name: "progress", Label: "Progress", Position: 2},
+		{Name: "done", Label: "Done", Position: 3, IsTerminal: true},
+	}}
+
+	at := func(location *time.Location, month time.Month, d int, hour int, minute int) time.Time {
+		return time.Date(2024, month, d, hour, minute, 0, 0, location)
+	}
+	timeAt := func(location *time.Location, month time.Month, d int, hour int, minute int) *time.Time {
+		value := at(location, month, d, hour, minute)
+		return &value
+	}
+	day := func(date string, ideal float64, remaining float64, completed float64) BurndownDay {
+		return BurndownDay{Date: date, Ideal: ideal, Remaining: &remaining, Completed: &completed}
+	}
+
+	tests := []struct {
+		name          string
+		iteration     Iteration
+		iterationLogs []IterationLog
+		transitions   []StatusTransition
+		weight        string
+		location      *time.Location
+		now           time.Time
+		want          []BurndownDay
+	}{
+		{
+			// clocks go forward on the 10th, the days still end at local
+			// midnight
+			name:      "iteration over a dst change",
+			iteration: Iteration{StartsOn: "2024-03-08", EndsOn: "2024-03-12"},
+			iterationLogs: []IterationLog{
+				{LogId: "a"},
+				{LogId: "b", CompletedAt: timeAt(newYork, time.March, 11, 0, 30)},
+				{LogId: "c"},
+			},
+			transitions: []StatusTransition{
+				{LogId: "a", ToStatus: "backlog", TransitionedAt: at(newYork, time.March, 7, 12, 0).UTC()},
+				{LogId: "a", ToStatus: "done", TransitionedAt: at(newYork, time.March, 10, 23, 30).UTC()},
+			},
+			weight:   "count",
+			location: newYork,
+			now:      at(time.UTC, time.April, 1, 0, 0),
+			want: []BurndownDay{
+				day("2024-03-08", 3, 3, 0),
+				day("2024-03-09", 2.25, 3, 0),
+				day("2024-03-10", 1.5, 2, 1),
+				day("2024-03-11", 0.75, 1, 2),
+				day("2024-03-12", 0, 1, 2),
+			},
+		},
+		{
+			name:      "one day iteration stops at now",
+			iteration: Iteration{StartsOn: "2024-05-01", EndsOn: "2024-05-01"},
+			iterationLogs: []IterationLog{
+				{LogId: "a", Priority: 2, CompletedAt: timeAt(time.UTC, time.May, 1, 10, 0)},
+				{LogId: "b", Priority: 3, CompletedAt: timeAt(time.UTC, time.May, 1, 13, 0)},
+			},
+			transitions: []StatusTransition{},
+			weight:      "priority",
+			location:    time.UTC,
+			now:         at(time.UTC, time.May, 1, 12, 0),
+			want:        []BurndownDay{day("2024-05-01", 0, 3, 2)},
+		},
+		{
+			name:      "a log without transitions falls back on completedAt",
+			iteration: Iteration{StartsOn: "2024-05-01", EndsOn: "2024-05-02"},
+			iterationLogs: []IterationLog{
+				{LogId: "a", CompletedAt: timeAt(time.UTC, time.May, 1, 10, 0)},
+				{LogId: "b", CompletedAt: timeAt(time.UTC, time.May, 1, 10, 0)},
+			},
+			// b was reopened, its transitions win over its old completedAt
+			transitions: []StatusTransition{
+				{LogId: "b", ToStatus: "done", TransitionedAt: at(time.UTC, time.May, 1, 10, 0)},
+				{LogId: "b", ToStatus: "progress", TransitionedAt: at(time.UTC, time.May, 2, 9, 0)},
+			},
+			weight:   "count",
+			location: time.UTC,
+			now:      at(time.UTC, time.May, 3, 0, 0),
+			want: []BurndownDay{
+				day("2024-05-01", 2, 0, 2),
+				day("2024-05-02", 0, 1, 1),
+			},
+		},
+		{
+			name:      "days after now have no actual values",
+			iteration: Iteration{StartsOn: "2024-05-01", EndsOn: "2024-05-03"},
+			iterationLogs: []IterationLog{
+				{LogId: "a", Estimate: func() *float64 { value := 5.0; return &value }()},
+				{LogId: "b"},
+			},
+			transitions: []StatusTransition{},
+			weight:      "estimate",
+			location:    time.UTC,
+			now:         at(time.UTC, time.May, 1, 8, 0),
+			want: []BurndownDay{
+				day("2024-05-01", 5, 5, 0),
+				{Date: "2024-05-02", Ideal: 2.5},
+				{Date: "2024-05-03", Ideal: 0},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := iterationBurndown(test.iteration, test.iterationLogs, test.transitions, test.weight, test.location, vocabulary, test.now)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("iterationBurndown() = %s, want %s", burndownText(got), burndownText(test.want))
+			}
+		})
+	}
+}
+
+// readable burndown for the test failures, the values are pointers
+func burndownText(burndown []BurndownDay) string {
+	text := ""
+	for _, row := range burndown {
+		text += row.Date + " ideal " + strconv.FormatFloat(row.Ideal, 'g', -1, 64)
+		if row.Remaining != nil && row.Completed != nil {
+			text += " remaining " + strconv.FormatFloat(*row.Remaining, 'g', -1, 64) + " completed " + strconv.FormatFloat(*row.Completed, 'g', -1, 64)
+		}
+
+		text += "; "
+	}
+
+	return text
+}
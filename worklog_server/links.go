@@ -0,0 +1,263 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// errors returned by the link stores
+var (
+	ErrLinkExists   = errors.New("the link already exists")
+	ErrLinkNotFound = errors.New("no link found between these logs")
+	ErrLinkCycle    = errors.New("the link would create a blocking cycle")
+)
+
+// a log on the other end of a chain of links, direct when a single link
+// joins them
+type LinkedLog struct {
+	LogId       string     `json:"logId"`
+	TaskName    string     `json:"taskName"`
+	TaskStatus  string     `json:"taskStatus"`
+	CompletedAt *time.Time `json:"completedAt"`
+	Direct      bool       `json:"direct"`
+}
+
+// a link between two logs
+type LogLink struct {
+	BlockerLogId string `json:"blockerLogId"`
+	BlockedLogId string `json:"blockedLogId"`
+}
+
+// logs blocked by a log and logs blocking it, directly or through other
+// logs, with the links of these chains
+type LogLinks struct {
+	Blocks    []LinkedLog `json:"blocks"`
+	BlockedBy []LinkedLog `json:"blockedBy"`
+	Edges     []LogLink   `json:"edges"`
+}
+
+// storage of the blocking links between logs
+type LinkStore interface {
+	AddLogLink(blockerLogId string, blockedLogId string) error
+	RemoveLogLink(blockerLogId string, blockedLogId string) error
+	GetLogLinks(logId string) (LogLinks, error)
+}
+
+// ids of the logs reached from $1 by following the links from the column
+// from to the column to, directly or through other logs, as the cte name
+func linkedLogsQuery(name string, from string, to string) string {
+	return `with recursive ` + name + ` (log_id) as (
+		select ` + to + ` from log_links where ` + from + ` = $1
+		union
+		select log_links.` + to + ` from log_links join ` + name + ` on log_links.` + from + ` = ` + name + `.log_id
+	)`
+}
+
+// ids of the logs blocked by $1
+var blockedLogsQuery = linkedLogsQuery("blocked", "blocker_log_id", "blocked_log_id")
+
+// link two logs inside a transaction, rejecting links that would close a
+// cycle. The stores serialise the link writes so two links checked at the
+// same time can't close a cycle together
+func addLogLinkTx(tx *sql.Tx, blockerLogId string, blockedLogId string) error {
+	if !uuidPattern.MatchString(blockerLogId) || !uuidPattern.MatchString(blockedLogId) {
+		return ErrLogNotFound
+	}
+
+	if blockerLogId == blockedLogId {
+		return ErrLinkCycle
+	}
+
+	var count int
+	err := tx.QueryRow("select count(*) from logs where log_id in ($1, $2)", blockerLogId, blockedLogId).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count != 2 {
+		return ErrLogNotFound
+	}
+
+	// the blocker can't already be waiting on the log it blocks
+	var exists int
+	err = tx.QueryRow(blockedLogsQuery+" select 1 from blocked where log_id = $2", blockedLogId, blockerLogId).Scan(&exists)
+	if err == nil {
+		return ErrLinkCycle
+	}
+
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	q := "insert into log_links (blocker_log_id, blocked_log_id) values ($1, $2) on conflict do nothing"
+	result, err := tx.Exec(q, blockerLogId, blockedLogId)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrLinkExists
+	}
+
+	return nil
+}
+
+func removeLogLink(db *sql.DB, blockerLogId string, blockedLogId string) error {
+	if !uuidPattern.MatchString(blockerLogId) || !uuidPattern.MatchString(blockedLogId) {
+		return ErrLinkNotFound
+	}
+
+	q := "delete from log_links where blocker_log_id = $1 and blocked_log_id = $2"
+	result, err := db.Exec(q, blockerLogId, blockedLogId)
+	if err != nil {
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrLinkNotFound
+	}
+
+	return nil
+}
+
+// logs linked to a log in both directions and the links between them
+func getLogLinks(db *sql.DB, logId string) (LogLinks, error) {
+	links := LogLinks{Edges: []LogLink{}}
+	blocks, err := getLinkedLogs(db, logId, "blocker_log_id", "blocked_log_id", &links.Edges)
+	if err != nil {
+		return links, err
+	}
+
+	blockedBy, err := getLinkedLogs(db, logId, "blocked_log_id", "blocker_log_id", &links.Edges)
+	if err != nil {
+		return links, err
+	}
+
+	links.Blocks, links.BlockedBy = blocks, blockedBy
+	return links, nil
+}
+
+// logs reached from a log by following the links from the column from to
+// the column to. The links of these chains are added to edges
+func getLinkedLogs(db *sql.DB, logId string, from string, to string, edges *[]LogLink) ([]LinkedLog, error) {
+	linked := []LinkedLog{}
+	query := linkedLogsQuery("linked", from, to)
+	q := query + `
+		select
+			logs.log_id,
+			logs.task_name,
+			logs.task_status,
+			logs.completed_at,
+			exists (select 1 from log_links where log_links.` + from + ` = $1 and log_links.` + to + ` = logs.log_id)
+		from logs
+		where logs.log_id in (select log_id from linked)
+		order by logs.created_at, logs.log_id`
+
+	rows, err := db.Query(q, logId)
+	if err != nil {
+		return linked, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			linkedLog   LinkedLog
+			completedAt sql.NullTime
+		)
+
+		if err := rows.Scan(&linkedLog.LogId, &linkedLog.TaskName, &linkedLog.TaskStatus, &completedAt, &linkedLog.Direct); err != nil {
+			return linked, err
+		}
+
+		if completedAt.Valid {
+			linkedLog.CompletedAt = &completedAt.Time
+		}
+
+		linked = append(linked, linkedLog)
+	}
+
+	if err := rows.Err(); err != nil {
+		return linked, err
+	}
+
+	q = query + `
+		select blocker_log_id, blocked_log_id
+		from log_links
+		where ` + from + ` = $1 or ` + from + ` in (select log_id from linked)
+		order by blocker_log_id, blocked_log_id`
+
+	edgeRows, err := db.Query(q, logId)
+	if err != nil {
+		return linked, err
+	}
+
+	defer edgeRows.Close()
+	for edgeRows.Next() {
+		var edge LogLink
+		if err := edgeRows.Scan(&edge.BlockerLogId, &edge.BlockedLogId); err != nil {
+			return linked, err
+		}
+
+		*edges = append(*edges, edge)
+	}
+
+	return linked, edgeRows.Err()
+}
+
+func handleAddLogLink(store LinkStore, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	err := store.AddLogLink(r.PathValue("logId"), r.PathValue("blockedLogId"))
+	if err != nil {
+		switch err {
+		case ErrLogNotFound:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "No log found"})
+		case ErrLinkExists:
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		case ErrLinkCycle:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while linking the logs"})
+		}
+
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Link created successfully"})
+}
+
+func handleRemoveLogLink(store LinkStore, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	err := store.RemoveLogLink(r.PathValue("logId"), r.PathValue("blockedLogId"))
+	if err != nil {
+		if err == ErrLinkNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "No link found"})
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while deleting the link"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Successfully deleted the link"})
+}
@@ -0,0 +1,88 @@
+package main
+
+import "testing"
+
+func TestAddLogLink(t *testing.T) {
+	store := newTestStore(t)
+	a := createTestLog(t, store, CreateLogInput{TaskName: "a"})
+	b := createTestLog(t, store, CreateLogInput{TaskName: "b"})
+	c := createTestLog(t, store, CreateLogInput{TaskName: "c"})
+
+	// a blocks b which blocks c
+	for _, link := range [][2]string{{a.LogId, b.LogId}, {b.LogId, c.LogId}} {
+		if err := store.AddLogLink(link[0], link[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		blocker string
+		blocked string
+		want    error
+	}{
+		{"itself", a.LogId, a.LogId, ErrLinkCycle},
+		{"direct cycle", b.LogId, a.LogId, ErrLinkCycle},
+		{"cycle through another log", c.LogId, a.LogId, ErrLinkCycle},
+		{"existing link", a.LogId, b.LogId, ErrLinkExists},
+		{"unknown log", a.LogId, "5f0c2a8e-2a47-4c3b-9b0e-1d2f3a4b5c6d", ErrLogNotFound},
+		{"id that isn't a uuid", a.LogId, "b", ErrLogNotFound},
+		{"shortcut", a.LogId, c.LogId, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := store.AddLogLink(test.blocker, test.blocked); err != test.want {
+				t.Errorf("AddLogLink() error = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestGetLogLinks(t *testing.T) {
+	store := newTestStore(t)
+	a := createTestLog(t, store, CreateLogInput{TaskName: "a"})
+	b := createTestLog(t, store, CreateLogInput{TaskName: "b"})
+	c := createTestLog(t, store, CreateLogInput{TaskName: "c"})
+	d := createTestLog(t, store, CreateLogInput{TaskName: "d"})
+
+	// a and d block b, b blocks c
+	for _, link := range [][2]string{{a.LogId, b.LogId}, {b.LogId, c.LogId}, {d.LogId, b.LogId}} {
+		if err := store.AddLogLink(link[0], link[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	links, err := store.GetLogLinks(a.LogId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := map[string]string{a.LogId: "a", b.LogId: "b", c.LogId: "c", d.LogId: "d"}
+	linked := func(linkedLogs []LinkedLog) map[string]bool {
+		direct := map[string]bool{}
+		for _, linkedLog := range linkedLogs {
+			direct[names[linkedLog.LogId]] = linkedLog.Direct
+		}
+
+		return direct
+	}
+
+	if got := linked(links.Blocks); len(got) != 2 || !got["b"] || got["c"] {
+		t.Errorf("a blocks %v, want b directly and c through b", got)
+	}
+
+	if len(links.BlockedBy) != 0 {
+		t.Errorf("a is blocked by %v, want no log", linked(links.BlockedBy))
+	}
+
+	// d blocks b too but isn't on a chain through a
+	edges := map[[2]string]bool{}
+	for _, edge := range links.Edges {
+		edges[[2]string{names[edge.BlockerLogId], names[edge.BlockedLogId]}] = true
+	}
+
+	if len(edges) != 2 || !edges[[2]string{"a", "b"}] || !edges[[2]string{"b", "c"}] {
+		t.Errorf("edges = %v, want a-b and b-c", edges)
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// what differs between the stores in the log writes they share
+type logDialect struct {
+	// sql of the current time of the database
+	now string
+	// arguments in the form the driver stores them
+	args func(args []any) []any
+}
+
+// a database or a transaction
+type queryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// project of a log, the default project when none is given
+func resolveProjectId(db queryRower, projectId string) (string, error) {
+	if projectId == "" {
+		err := db.QueryRow("select project_id from projects where is_default").Scan(&projectId)
+		return projectId, err
+	}
+
+	// anything but a uuid fails the cast in postgres
+	if !uuidPattern.MatchString(projectId) {
+		return "", ErrProjectNotFound
+	}
+
+	var exists int
+	err := db.QueryRow("select 1 from projects where project_id = $1", projectId).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return "", ErrProjectNotFound
+	}
+
+	return projectId, err
+}
+
+// check if a task name is taken by another log of the project
+func taskNameExists(db queryRower, projectId string, taskName string, logId string) (bool, error) {
+	q := "select 1 from logs where project_id = $1 and task_name = $2 and cast(log_id as text) <> $3 limit 1"
+	var exists int
+	err := db.QueryRow(q, projectId, taskName, logId).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// create a log inside a transaction and record it in the history
+func createLogTx(tx *sql.Tx, dialect logDialect, input CreateLogInput) error {
+	projectId, err := resolveProjectId(tx, input.ProjectId)
+	if err != nil {
+		return err
+	}
+
+	// check for duplicate keys
+	exists, err := taskNameExists(tx, projectId, input.TaskName, "")
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrDuplicateTaskName
+	}
+
+	if input.ParentLogId != "" {
+		if err := checkLogParent(tx, "", input.ParentLogId, projectId); err != nil {
+			return err
+		}
+	}
+
+	q := "insert into logs (project_id, parent_log_id, task_name, task_type, task_status, notes, started_at, completed_at, priority, due_at) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) returning log_id"
+	var logId string
+	err = tx.QueryRow(
+		q,
+		dialect.args([]any{
+			projectId,
+			sql.NullString{String: input.ParentLogId, Valid: input.ParentLogId != ""},
+			input.TaskName,
+			input.TaskType,
+			input.TaskStatus,
+			input.Notes,
+			input.StartedAt,
+			input.CompletedAt,
+			input.Priority,
+			input.DueAt,
+		})...,
+	).Scan(&logId)
+
+	if err != nil {
+		return err
+	}
+
+	if len(input.Tags) > 0 {
+		if err := setLogTags(tx, logId, input.Tags); err != nil {
+			return err
+		}
+	}
+
+	created, err := getLogTx(tx, logId)
+	if err != nil {
+		return err
+	}
+
+	if err := recordLogEvent(tx, logCreated, WorkLog{}, created); err != nil {
+		return err
+	}
+
+	return recordStatusTransition(tx, WorkLog{}, created)
+}
+
+// update a log inside a transaction and record the changes in the history
+func updateLogTx(tx *sql.Tx, dialect logDialect, logId string, input UpdateLogInput) error {
+	// validate log id
+	if !uuidPattern.MatchString(logId) {
+		return ErrLogNotFound
+	}
+
+	workLog, err := getLogTx(tx, logId)
+	if err == sql.ErrNoRows {
+		return ErrLogNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	projectId := workLog.ProjectId
+	if input.ProjectId != "" {
+		if projectId, err = resolveProjectId(tx, input.ProjectId); err != nil {
+			return err
+		}
+	}
+
+	// task names are unique per project
+	if input.ProjectId != "" || input.TaskName != "" {
+		taskName := workLog.TaskName
+		if input.TaskName != "" {
+			taskName = input.TaskName
+		}
+
+		exists, err := taskNameExists(tx, projectId, taskName, logId)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			return ErrDuplicateTaskName
+		}
+	}
+
+	// a subtask stays in the project of its parent, and a log with subtasks
+	// stays in theirs
+	parentLogId := ""
+	if input.ParentLogId != nil {
+		parentLogId = *input.ParentLogId
+	} else if workLog.ParentLogId != nil && projectId != workLog.ProjectId {
+		parentLogId = *workLog.ParentLogId
+	}
+
+	if parentLogId != "" {
+		if err := checkLogParent(tx, logId, parentLogId, projectId); err != nil {
+			return err
+		}
+	}
+
+	if projectId != workLog.ProjectId {
+		var exists int
+		err := tx.QueryRow("select 1 from logs where parent_log_id = $1 limit 1", logId).Scan(&exists)
+		if err == nil {
+			return ErrParentProject
+		}
+
+		if err != sql.ErrNoRows {
+			return err
+		}
+	}
+
+	fields := []string{}
+	args := []any{}
+	argIdx := 1
+
+	if input.ProjectId != "" {
+		fields = append(fields, fmt.Sprintf("project_id = $%d", argIdx))
+		args = append(args, input.ProjectId)
+		argIdx++
+	}
+
+	if input.ParentLogId != nil {
+		fields = append(fields, fmt.Sprintf("parent_log_id = $%d", argIdx))
+		args = append(args, sql.NullString{String: *input.ParentLogId, Valid: *input.ParentLogId != ""})
+		argIdx++
+	}
+
+	if strings.TrimSpace(input.TaskName) != "" {
+		fields = append(fields, fmt.Sprintf("task_name = $%d", argIdx))
+		args = append(args, input.TaskName)
+		argIdx++
+	}
+
+	if input.TaskType != "" {
+		fields = append(fields, fmt.Sprintf("task_type = $%d", argIdx))
+		args = append(args, input.TaskType)
+		argIdx++
+	}
+
+	if input.TaskStatus != "" {
+		fields = append(fields, fmt.Sprintf("task_status = $%d", argIdx))
+		args = append(args, input.TaskStatus)
+		argIdx++
+	}
+
+	if strings.TrimSpace(input.Notes) != "" {
+		fields = append(fields, fmt.Sprintf("notes = $%d", argIdx))
+		args = append(args, input.Notes)
+		argIdx++
+	}
+
+	if input.StartedAt != nil {
+		fields = append(fields, fmt.Sprintf("started_at = $%d", argIdx))
+		args = append(args, input.StartedAt.UTC())
+		argIdx++
+	} else if input.ClearStartedAt {
+		fields = append(fields, "started_at = null")
+	}
+
+	if input.CompletedAt != nil {
+		fields = append(fields, fmt.Sprintf("completed_at = $%d", argIdx))
+		args = append(args, input.CompletedAt.UTC())
+		argIdx++
+	} else if input.ClearCompletedAt {
+		fields = append(fields, "completed_at = null")
+	}
+
+	if input.DueAt != nil {
+		fields = append(fields, fmt.Sprintf("due_at = $%d", argIdx))
+		args = append(args, input.DueAt.UTC())
+		argIdx++
+	} else if input.ClearDueAt {
+		fields = append(fields, "due_at = null")
+	}
+
+	if input.Priority != nil {
+		fields = append(fields, fmt.Sprintf("priority = $%d", argIdx))
+		args = append(args, *input.Priority)
+		argIdx++
+	}
+
+	fields = append(fields, "updated_at = "+dialect.now)
+	args = append(args, logId)
+	query := fmt.Sprintf("update logs set %s where log_id = $%d", strings.Join(fields, ", "), argIdx)
+	if _, err := tx.Exec(query, dialect.args(args)...); err != nil {
+		return err
+	}
+
+	if input.Tags != nil {
+		if err := setLogTags(tx, logId, *input.Tags); err != nil {
+			return err
+		}
+	}
+
+	updated, err := getLogTx(tx, logId)
+	if err != nil {
+		return err
+	}
+
+	if err := recordLogEvent(tx, logUpdated, workLog, updated); err != nil {
+		return err
+	}
+
+	return recordStatusTransition(tx, workLog, updated)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCreateLog(t *testing.T) {
+	store := newTestStore(t)
+	createTestLog(t, store, CreateLogInput{TaskName: "taken"})
+	other, err := store.CreateProject(ProjectInput{Name: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		input CreateLogInput
+		want  error
+	}{
+		{"new name", CreateLogInput{TaskName: "new"}, nil},
+		{"name taken in the project", CreateLogInput{TaskName: "taken"}, ErrDuplicateTaskName},
+		{"name taken in another project", CreateLogInput{TaskName: "taken", ProjectId: other.ProjectId}, nil},
+		{"unknown project", CreateLogInput{TaskName: "lost", ProjectId: "5f0c2a8e-2a47-4c3b-9b0e-1d2f3a4b5c6d"}, ErrProjectNotFound},
+		{"project id that isn't a uuid", CreateLogInput{TaskName: "lost", ProjectId: "other"}, ErrProjectNotFound},
+		{"unknown parent", CreateLogInput{TaskName: "orphan", ParentLogId: "5f0c2a8e-2a47-4c3b-9b0e-1d2f3a4b5c6d"}, ErrParentNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.input.TaskType, test.input.TaskStatus, test.input.Priority = "task", "backlog", 1
+			if err := store.CreateLog(test.input); err != test.want {
+				t.Errorf("CreateLog() error = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestUpdateLog(t *testing.T) {
+	store := newTestStore(t)
+	workLog := createTestLog(t, store, CreateLogInput{TaskName: "a", Notes: "first"})
+	createTestLog(t, store, CreateLogInput{TaskName: "b"})
+
+	priority := 3
+	startedAt := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	updated, err := store.UpdateLog(workLog.LogId, UpdateLogInput{TaskStatus: "progress", Priority: &priority, StartedAt: &startedAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if updated.TaskStatus != "progress" || updated.Priority != 3 || updated.Notes != "first" || updated.StartedAt == nil || !updated.StartedAt.Equal(startedAt) {
+		t.Errorf("UpdateLog() = %+v, want the new status, priority and start and the old notes", updated)
+	}
+
+	if updated.UpdatedAt.Before(workLog.UpdatedAt) {
+		t.Errorf("updatedAt = %s, want after %s", updated.UpdatedAt, workLog.UpdatedAt)
+	}
+
+	updated, err = store.UpdateLog(workLog.LogId, UpdateLogInput{ClearStartedAt: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if updated.StartedAt != nil {
+		t.Errorf("startedAt = %s, want it cleared", updated.StartedAt)
+	}
+
+	errorTests := []struct {
+		name  string
+		logId string
+		input UpdateLogInput
+		want  error
+	}{
+		{"name of another log", workLog.LogId, UpdateLogInput{TaskName: "b"}, ErrDuplicateTaskName},
+		{"its own name", workLog.LogId, UpdateLogInput{TaskName: "a"}, nil},
+		{"unknown project", workLog.LogId, UpdateLogInput{ProjectId: "5f0c2a8e-2a47-4c3b-9b0e-1d2f3a4b5c6d"}, ErrProjectNotFound},
+		{"unknown log", "5f0c2a8e-2a47-4c3b-9b0e-1d2f3a4b5c6d", UpdateLogInput{TaskName: "c"}, ErrLogNotFound},
+		{"log id that isn't a uuid", "a", UpdateLogInput{TaskName: "c"}, ErrLogNotFound},
+	}
+
+	for _, test := range errorTests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := store.UpdateLog(test.logId, test.input); err != test.want {
+				t.Errorf("UpdateLog() error = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -17,36 +18,71 @@ import (
 
 // structure of the secrets
 type secrets struct {
-	host       string
-	dbPort     string
-	dbUsername string
-	dbPassword string
-	dbName     string
-	serverPort string
+	host         string
+	dbPort       string
+	dbUsername   string
+	dbPassword   string
+	dbName       string
+	serverPort   string
+	dbDriver     string
+	sqlitePath   string
+	workflowFile string
+	defaultUser  string
+
+	defaultTimezone string
 }
 
 type WorkLog struct {
 	LogId       string     `json:"logId"`
+	ProjectId   string     `json:"projectId"`
+	ParentLogId *string    `json:"parentLogId"`
 	TaskName    string     `json:"taskName"`
 	TaskType    string     `json:"taskType"`
 	TaskStatus  string     `json:"taskStatus"`
 	Notes       string     `json:"notes"`
 	StartedAt   *time.Time `json:"startedAt"`
 	CompletedAt *time.Time `json:"completedAt"`
+	DueAt       *time.Time `json:"dueAt"`
 	CreatedAt   time.Time  `json:"createdAt"`
 	UpdatedAt   time.Time  `json:"updatedAt"`
 	Priority    int        `json:"priority"`
+	Tags        []string   `json:"tags"`
+
+	// seconds of the finished time entries
+	TrackedSeconds int64 `json:"trackedSeconds"`
 }
 
 // get the secrets
 func GetSecrets() secrets {
 	secrets := secrets{
-		host:       os.Getenv("DB_HOST"),
-		dbPort:     os.Getenv("DB_PORT"),
-		dbUsername: os.Getenv("DB_USERNAME"),
-		dbPassword: os.Getenv("DB_PASSWORD"),
-		dbName:     os.Getenv("DB_NAME"),
-		serverPort: os.Getenv("SERVER_PORT"),
+		host:         os.Getenv("DB_HOST"),
+		dbPort:       os.Getenv("DB_PORT"),
+		dbUsername:   os.Getenv("DB_USERNAME"),
+		dbPassword:   os.Getenv("DB_PASSWORD"),
+		dbName:       os.Getenv("DB_NAME"),
+		serverPort:   os.Getenv("SERVER_PORT"),
+		dbDriver:     os.Getenv("DB_DRIVER"),
+		sqlitePath:   os.Getenv("SQLITE_PATH"),
+		workflowFile: os.Getenv("WORKFLOW_FILE"),
+		defaultUser:  os.Getenv("DEFAULT_USER"),
+
+		defaultTimezone: os.Getenv("DEFAULT_TIMEZONE"),
+	}
+
+	if secrets.dbDriver == "" {
+		secrets.dbDriver = "postgres"
+	}
+
+	if secrets.sqlitePath == "" {
+		secrets.sqlitePath = "worklog.db"
+	}
+
+	if secrets.defaultUser == "" {
+		secrets.defaultUser = "default"
+	}
+
+	if secrets.defaultTimezone == "" {
+		secrets.defaultTimezone = "UTC"
 	}
 
 	return secrets
@@ -63,8 +99,8 @@ func GetConnectionStr() string {
 	return connectionString
 }
 
-// connect to database
-func ConnectToDB() *sql.DB {
+// open the postgres connection without touching the schema
+func OpenPostgres() *sql.DB {
 	connectionStr := GetConnectionStr()
 	db, err := sql.Open("postgres", connectionStr)
 	if err != nil {
@@ -80,161 +116,43 @@ func ConnectToDB() *sql.DB {
 	return db
 }
 
-// get all the logs
-type GetAllLogsOpts struct {
-	db        *sql.DB
-	s         string
-	sortBy    string
-	sortOrder string
-	limit     int16
-	page      int16
-}
-
-func getFullTextSearchQueryOnLogs(userQuery string, limit int16, offset int16) string {
-	tsQueryText := strings.Replace(userQuery, " ", " & ", -1)
-	fmt.Println("tsQueryText: ", tsQueryText)
-	// q := fmt.Sprintf(`
-	// 	select
-	// 		log_id,
-	// 		task_name,
-	// 		task_type,
-	// 		task_status,
-	// 		notes,
-	// 		started_at,
-	// 		completed_at,
-	// 		created_at,
-	// 		updated_at,
-	// 		priority,
-	// 		(ceil((select count(*) from logs) / %d)) as total_pages
-	// 	from logs
-	// 	where ts @@ to_tsquery('english', '%s')
-	// 	or similarity('%s', task_name || ' ' || notes) > 0
-	// 	order by
-	// 		ts_rank(ts, to_tsquery('english', '%s')) desc,
-	// 		similarity('%s', task_name || ' ' || notes) desc
-	// 	offset %d limit %d`,
-	// 	limit,
-	// 	tsQueryText,
-	// 	userQuery,
-	// 	tsQueryText,
-	// 	userQuery,
-	// 	offset,
-	// 	limit,
-	// )
-
-	q := fmt.Sprintf(`
-		with filtered_logs as (
-			select * from logs
-			where ts @@ to_tsquery('english', '%s')
-			or similarity('%s', task_name || ' ' || notes) > 0
-		) 
-	 	select
-	 		log_id,
-	 		task_name,
-	 		task_type,
-	 		task_status,
-	 		notes,
-	 		started_at,
-	 		completed_at,
-	 		created_at,
-	 		updated_at,
-	 		priority,
-	 		(ceil(count(*) over() / %f)) as total_pages
-		from filtered_logs
-		order by
-			ts_rank(ts, to_tsquery('english', '%s')) desc,
-			similarity('%s', task_name || ' ' || notes) desc
-		offset %d limit %d`,
-		tsQueryText,
-		userQuery,
-		float64(limit),
-		tsQueryText,
-		userQuery,
-		offset,
-		limit,
-	)
-
-	return q
-}
-
-func GetAllLogs(options *GetAllLogsOpts) (*sql.Rows, error) {
-	var q string
-	var sortBy string
-	var sortOrder string
-	var limit int16
-	var offset int16
-
-	if options.sortBy == "" {
-		sortBy = "updated_at"
-	} else {
-		sortBy = options.sortBy
-	}
-
-	if options.sortOrder == "" {
-		sortOrder = "desc"
-	} else {
-		sortOrder = options.sortOrder
-	}
-
-	if options.limit > 0 {
-		limit = options.limit
-	} else {
-		limit = 10
-	}
-
-	if options.page > 0 {
-		offset = options.page * limit
-	} else {
-		offset = 0
+// connect to database and apply the pending migrations
+func ConnectToDB() *sql.DB {
+	db := OpenPostgres()
+	if err := MigrateUp(db, "postgres"); err != nil {
+		panic(err)
 	}
 
-	// check if options have search value
-	if strings.TrimSpace(options.s) != "" {
-		q = getFullTextSearchQueryOnLogs(options.s, limit, offset)
-		// q = fmt.Sprintf(
-		// 	`select * from logs
-		// 	where task_name ilike '%%%s%%'
-		// 	or notes ilike '%%%s%%'
-		// 	order by updated_at desc;`,
-		// 	options.s,
-		// 	options.s,
-		// )
-	} else {
-		q = fmt.Sprintf(`select
-			log_id,
-			task_name,
-			task_type,
-			task_status,
-			notes,
-			started_at,
-			completed_at,
-			created_at,
-			updated_at,
-			priority,
-			(ceil((select count(*) from logs) / %f)) as total_pages 
-		from logs order by %s %s 
-		offset %d limit %d;`, float64(limit), sortBy, sortOrder, offset, limit)
-	}
+	return db
+}
 
-	fmt.Println("[query]: ", q)
-	rows, err := options.db.Query(q)
-	if err != nil {
-		return nil, err
+// open the store for the configured DB_DRIVER
+func OpenStore() Store {
+	secrets := GetSecrets()
+	switch secrets.dbDriver {
+	case "postgres":
+		return NewPostgresStore(ConnectToDB())
+	case "sqlite":
+		return NewSQLiteStore(ConnectToSQLite(secrets.sqlitePath))
 	}
 
-	return rows, nil
+	panic("unsupported DB_DRIVER " + secrets.dbDriver)
 }
 
-func handleCreateLog(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+func handleCreateLog(store Store, workflow Workflow, w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	var body struct {
+		ProjectId   string     `json:"projectId"`
+		ParentLogId string     `json:"parentLogId"`
 		TaskName    string     `json:"taskName"`
 		TaskType    string     `json:"taskType"`
 		TaskStatus  string     `json:"taskStatus"`
 		Notes       string     `json:"notes"`
 		StartedAt   *time.Time `json:"startedAt"`
 		CompletedAt *time.Time `json:"completedAt"`
+		DueAt       *time.Time `json:"dueAt"`
 		Priority    *int       `json:"priority"`
+		Tags        []string   `json:"tags"`
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&body)
@@ -249,13 +167,18 @@ func handleCreateLog(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if body.TaskStatus == "" || !validateTaskStatus(body.TaskStatus) {
+	vocabulary, ok := loadVocabulary(store, w)
+	if !ok {
+		return
+	}
+
+	if body.TaskStatus == "" || !vocabulary.validateTaskStatus(body.TaskStatus) {
 		w.WriteHeader(http.StatusUnprocessableEntity)
 		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid task status"})
 		return
 	}
 
-	if body.TaskType == "" || !validateTaskType(body.TaskType) {
+	if body.TaskType == "" || !vocabulary.validateTaskType(body.TaskType) {
 		w.WriteHeader(http.StatusUnprocessableEntity)
 		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid task type"})
 		return
@@ -275,141 +198,93 @@ func handleCreateLog(db *sql.DB, w http.ResponseWriter, r *http.Request) {
 		body.CompletedAt = &completedAt
 	}
 
+	if body.DueAt != nil {
+		dueAt := body.DueAt.UTC()
+		body.DueAt = &dueAt
+	}
+
 	// handle task priority
 	if body.Priority != nil {
 		// valid priority int
-		if *body.Priority != 1 && *body.Priority != 5 && *body.Priority != 7 && *body.Priority != 10 {
+		if !vocabulary.validatePriority(*body.Priority) {
 			w.WriteHeader(http.StatusUnprocessableEntity)
 			json.NewEncoder(w).Encode(map[string]string{"message": "Invalid priority value"})
 			return
 		}
 	}
 
-	// assing default value of priority if it's nil
+	// assing the lowest priority if it's nil
 	if body.Priority == nil {
-		var defaultVal int = 1
+		defaultVal := vocabulary.lowestPriority()
 		body.Priority = &defaultVal
 	}
 
-	// check for duplicate keys
-	q := "select 1 from logs where task_name = $1 limit 1"
-	row := db.QueryRow(q, body.TaskName)
-	var exists int
-	err = row.Scan(&exists)
-	if err == sql.ErrNoRows {
-		// no duplicate found, proceed
-	} else if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	} else {
-		// duplicate found
-		http.Error(w, "Task name already exists", http.StatusBadRequest)
-		return
-	}
-
-	q = "insert into logs (task_name, task_type, task_status, notes, started_at, completed_at, priority) values ($1, $2, $3, $4, $5, $6, $7)"
-	_, err = db.Exec(
-		q,
-		body.TaskName,
-		body.TaskType,
-		body.TaskStatus,
-		body.Notes,
-		body.StartedAt,
-		body.CompletedAt,
-		body.Priority,
-	)
-
+	tags, err := normalizeTags(body.Tags)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
-}
-
-func validateTaskType(taskType string) bool {
-	taskTypes := map[string]bool{
-		"task":  true,
-		"bug":   true,
-		"story": true,
-	}
-
-	return taskTypes[taskType]
-}
-
-func validateTaskStatus(taskStatus string) bool {
-	taskTypes := map[string]bool{
-		"backlog":  true,
-		"pending":  true,
-		"progress": true,
-		"pr":       true,
-		"staging":  true,
+	// hooks of the workflow for the first status
+	body.StartedAt, body.CompletedAt = workflow.runHooks(vocabulary, "", body.TaskStatus, body.StartedAt, body.CompletedAt, time.Now().UTC())
+	if body.StartedAt != nil && body.CompletedAt != nil && body.CompletedAt.Before(*body.StartedAt) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Completed at can't be before started at"})
+		return
 	}
 
-	return taskTypes[taskStatus]
-}
-
-func getLogById(db *sql.DB, logId string) (WorkLog, error) {
-	q := "select log_id, task_name, task_type, task_status, priority, notes, started_at, completed_at, created_at, updated_at from logs where log_id = $1"
-	row := db.QueryRow(q, logId)
-	var workLog WorkLog
-	var (
-		notes       sql.NullString
-		startedAt   sql.NullTime
-		completedAt sql.NullTime
-	)
-
-	err := row.Scan(
-		&workLog.LogId,
-		&workLog.TaskName,
-		&workLog.TaskType,
-		&workLog.TaskStatus,
-		&workLog.Priority,
-		&notes,
-		&startedAt,
-		&completedAt,
-		&workLog.CreatedAt,
-		&workLog.UpdatedAt,
-	)
+	err = store.CreateLog(CreateLogInput{
+		ProjectId:   strings.TrimSpace(body.ProjectId),
+		ParentLogId: strings.TrimSpace(body.ParentLogId),
+		TaskName:    body.TaskName,
+		TaskType:    body.TaskType,
+		TaskStatus:  body.TaskStatus,
+		Notes:       body.Notes,
+		StartedAt:   body.StartedAt,
+		CompletedAt: body.CompletedAt,
+		DueAt:       body.DueAt,
+		Priority:    *body.Priority,
+		Tags:        tags,
+	})
 
 	if err != nil {
-		return workLog, err
-	}
-
-	if notes.Valid {
-		workLog.Notes = notes.String
-	} else {
-		workLog.Notes = "n/a"
-	}
-
-	if startedAt.Valid {
-		workLog.StartedAt = &startedAt.Time
-	} else {
-		workLog.StartedAt = nil
-	}
+		switch err {
+		case ErrDuplicateTaskName:
+			http.Error(w, "Task name already exists", http.StatusBadRequest)
+		case ErrProjectNotFound:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]string{"message": "No project found with this project id"})
+		case ErrParentNotFound, ErrParentCycle, ErrParentProject:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 
-	if completedAt.Valid {
-		workLog.CompletedAt = &completedAt.Time
-	} else {
-		workLog.CompletedAt = nil
+		return
 	}
 
-	return workLog, nil
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func updateLog(w http.ResponseWriter, r *http.Request, db *sql.DB) {
+func updateLog(w http.ResponseWriter, r *http.Request, store Store, workflow Workflow) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// get the body from request
 	var body struct {
 		LogId       string     `json:"logId"`
+		ProjectId   string     `json:"projectId"`
+		ParentLogId *string    `json:"parentLogId"`
 		TaskName    string     `json:"taskName"`
 		TaskType    string     `json:"taskType"`
 		TaskStatus  string     `json:"taskStatus"`
 		Notes       string     `json:"notes"`
 		StartedAt   *time.Time `json:"startedAt"`
 		CompletedAt *time.Time `json:"completedAt"`
+		DueAt       *string    `json:"dueAt"`
 		Priority    *int       `json:"priority"`
+		Tags        *[]string  `json:"tags"`
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&body)
@@ -426,15 +301,9 @@ func updateLog(w http.ResponseWriter, r *http.Request, db *sql.DB) {
 		return
 	}
 
-	q := "select log_id from logs where log_id = $1"
-	row := db.QueryRow(q, body.LogId)
-	var existingData struct {
-		LogId string `json:"log_id"`
-	}
-
-	err = row.Scan(&existingData.LogId)
+	current, err := store.GetLogById(body.LogId)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == ErrLogNotFound {
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(map[string]string{
 				"message": "No records found with this log id",
@@ -452,46 +321,38 @@ func updateLog(w http.ResponseWriter, r *http.Request, db *sql.DB) {
 		return
 	}
 
-	fields := []string{}
-	args := []any{}
-	argIdx := 1
+	vocabulary, ok := loadVocabulary(store, w)
+	if !ok {
+		return
+	}
 
-	if strings.TrimSpace(body.TaskName) != "" {
-		fields = append(fields, fmt.Sprintf("task_name = $%d", argIdx))
-		args = append(args, body.TaskName)
-		argIdx++
+	input := UpdateLogInput{
+		ProjectId:   strings.TrimSpace(body.ProjectId),
+		ParentLogId: body.ParentLogId,
+		TaskName:    strings.TrimSpace(body.TaskName),
+		Notes:       strings.TrimSpace(body.Notes),
 	}
 
 	if body.TaskType != "" {
 		// validate task type
-		if !validateTaskType(body.TaskType) {
+		if !vocabulary.validateTaskType(body.TaskType) {
 			w.WriteHeader(http.StatusUnprocessableEntity)
 			json.NewEncoder(w).Encode(map[string]string{"message": "Invalid task type"})
 			return
 		}
 
-		fields = append(fields, fmt.Sprintf("task_type = $%d", argIdx))
-		args = append(args, body.TaskType)
-		argIdx++
+		input.TaskType = body.TaskType
 	}
 
 	if body.TaskStatus != "" {
 		// validate task status
-		if !validateTaskStatus(body.TaskStatus) {
+		if !vocabulary.validateTaskStatus(body.TaskStatus) {
 			w.WriteHeader(http.StatusUnprocessableEntity)
 			json.NewEncoder(w).Encode(map[string]string{"message": "Invalid task status"})
 			return
 		}
 
-		fields = append(fields, fmt.Sprintf("task_status = $%d", argIdx))
-		args = append(args, body.TaskStatus)
-		argIdx++
-	}
-
-	if strings.TrimSpace(body.Notes) != "" {
-		fields = append(fields, fmt.Sprintf("notes = $%d", argIdx))
-		args = append(args, body.Notes)
-		argIdx++
+		input.TaskStatus = body.TaskStatus
 	}
 
 	if body.StartedAt != nil {
@@ -502,9 +363,8 @@ func updateLog(w http.ResponseWriter, r *http.Request, db *sql.DB) {
 			return
 		}
 
-		fields = append(fields, fmt.Sprintf("started_at = $%d", argIdx))
-		args = append(args, parsedTime.UTC())
-		argIdx++
+		startedAt := parsedTime.UTC()
+		input.StartedAt = &startedAt
 	}
 
 	if body.CompletedAt != nil {
@@ -515,41 +375,115 @@ func updateLog(w http.ResponseWriter, r *http.Request, db *sql.DB) {
 			return
 		}
 
-		fields = append(fields, fmt.Sprintf("completed_at = $%d", argIdx))
-		args = append(args, parsedTime.UTC())
-		argIdx++
+		completedAt := parsedTime.UTC()
+		input.CompletedAt = &completedAt
+	}
+
+	// an empty due date removes it
+	if body.DueAt != nil {
+		if *body.DueAt == "" {
+			input.ClearDueAt = true
+		} else {
+			parsedTime, err := time.Parse(time.RFC3339, *body.DueAt)
+			if err != nil {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				json.NewEncoder(w).Encode(map[string]string{"message": "Invalid due at value"})
+				return
+			}
+
+			dueAt := parsedTime.UTC()
+			input.DueAt = &dueAt
+		}
 	}
 
 	if body.Priority != nil {
-		if *body.Priority != 1 && *body.Priority != 5 && *body.Priority != 7 && *body.Priority != 10 {
+		if !vocabulary.validatePriority(*body.Priority) {
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"message": "Invalid priority value"})
 			return
 		}
 
-		fields = append(fields, fmt.Sprintf("priority = $%d", argIdx))
-		args = append(args, *body.Priority)
-		argIdx += 1
+		input.Priority = body.Priority
+	}
+
+	// an empty list removes every tag
+	if body.Tags != nil {
+		tags, err := normalizeTags(*body.Tags)
+		if err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+			return
+		}
+
+		input.Tags = &tags
+	}
+
+	if input.TaskStatus != "" && input.TaskStatus != current.TaskStatus {
+		if !workflow.canTransition(current.TaskStatus, input.TaskStatus) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"message": workflow.transitionError(current.TaskStatus, input.TaskStatus)})
+			return
+		}
+
+		// times given in the request win over the hooks
+		startedAt, completedAt := current.StartedAt, current.CompletedAt
+		if input.StartedAt != nil {
+			startedAt = input.StartedAt
+		}
+
+		if input.CompletedAt != nil {
+			completedAt = input.CompletedAt
+		}
+
+		hookStartedAt, hookCompletedAt := workflow.runHooks(vocabulary, current.TaskStatus, input.TaskStatus, startedAt, completedAt, time.Now().UTC())
+		if input.StartedAt == nil && hookStartedAt != startedAt {
+			input.StartedAt = hookStartedAt
+			input.ClearStartedAt = hookStartedAt == nil
+		}
+
+		if input.CompletedAt == nil && hookCompletedAt != completedAt {
+			input.CompletedAt = hookCompletedAt
+			input.ClearCompletedAt = hookCompletedAt == nil
+		}
 	}
 
-	if len(fields) == 0 {
+	if input.isEmpty() {
 		http.Error(w, "No valid fields to update", http.StatusBadRequest)
 		return
 	}
 
-	args = append(args, body.LogId)
-	query := fmt.Sprintf("update logs set %s, updated_at = now() where log_id = $%d", strings.Join(fields, ", "), argIdx)
-	fmt.Println(query)
+	// times of the log once updated
+	startedAt, completedAt := current.StartedAt, current.CompletedAt
+	if input.StartedAt != nil || input.ClearStartedAt {
+		startedAt = input.StartedAt
+	}
 
-	if _, err := db.Exec(query, args...); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if input.CompletedAt != nil || input.ClearCompletedAt {
+		completedAt = input.CompletedAt
+	}
+
+	if startedAt != nil && completedAt != nil && completedAt.Before(*startedAt) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Completed at can't be before started at"})
 		return
 	}
 
 	// Send response
-	updatedLog, err := getLogById(db, body.LogId)
+	updatedLog, err := store.UpdateLog(body.LogId, input)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		switch err {
+		case ErrDuplicateTaskName:
+			http.Error(w, "Task name already exists", http.StatusBadRequest)
+		case ErrProjectNotFound:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]string{"message": "No project found with this project id"})
+		case ErrParentNotFound, ErrParentCycle, ErrParentProject:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+
 		return
 	}
 
@@ -593,16 +527,37 @@ func pingHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "pong")
 }
 
+// load the .env file, the variables can come from the environment instead
+// like in the tests
 func init() {
 	err := godotenv.Load()
-	if err != nil {
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
 		log.Fatal(err)
 	}
 }
 
 func main() {
-	db := ConnectToDB()
-	defer db.Close()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(os.Args[2:])
+		return
+	}
+
+	store := OpenStore()
+	defer store.Close()
+
+	vocabulary, err := store.GetVocabulary()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	workflow, err := LoadWorkflow(GetSecrets().workflowFile, vocabulary)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := time.LoadLocation(GetSecrets().defaultTimezone); err != nil {
+		log.Fatalf("invalid DEFAULT_TIMEZONE: %v", err)
+	}
 	serverPort := GetSecrets().serverPort
 	mux := http.NewServeMux()
 	log.Println("server is running on http://localhost:" + serverPort)
@@ -618,111 +573,101 @@ func main() {
 		limitStr := r.URL.Query().Get("limit")
 		pageStr := r.URL.Query().Get("page")
 
-		limit, err := strconv.ParseInt(limitStr, 10, 64)
-		if err != nil {
-			http.Error(w, "Invalid limit value", http.StatusUnprocessableEntity)
-			return
-		}
+		limit := 0
+		if limitStr != "" {
+			value, err := strconv.Atoi(limitStr)
+			if err != nil || value < 1 {
+				http.Error(w, "Invalid limit value", http.StatusUnprocessableEntity)
+				return
+			}
 
-		page, err := strconv.ParseInt(pageStr, 10, 64)
-		if err != nil {
-			http.Error(w, "Invalid page value", http.StatusUnprocessableEntity)
+			limit = value
 		}
 
-		// lexical search
-		// for task_name, task_type, task_status, notes
-		rows, err := GetAllLogs(&GetAllLogsOpts{db: db, s: searchVal, sortBy: sortBy, sortOrder: sortOrder, limit: int16(limit), page: int16(page)})
-		if err != nil {
-			if err == sql.ErrNoRows {
-				http.Error(w, err.Error(), http.StatusNotFound)
+		page := 0
+		if pageStr != "" {
+			value, err := strconv.Atoi(pageStr)
+			if err != nil || value < 0 {
+				http.Error(w, "Invalid page value", http.StatusUnprocessableEntity)
 				return
 			}
 
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			page = value
+		}
+
+		vocabulary, ok := loadVocabulary(store, w)
+		if !ok {
 			return
 		}
 
-		defer rows.Close()
-		type Log struct {
-			LogId       string     `json:"logId"`
-			TaskName    string     `json:"taskName"`
-			TaskType    string     `json:"taskType"`
-			TaskStatus  string     `json:"taskStatus"`
-			Notes       string     `json:"notes"`
-			StartedAt   *time.Time `json:"startedAt"`
-			CompletedAt *time.Time `json:"completedAt"`
-			CreatedAt   time.Time  `json:"createdAt"`
-			UpdatedAt   time.Time  `json:"updatedAt"`
-			Priority    int        `json:"priority"`
-			TotalPages  int        `json:"totalPages"`
+		// status, type, priority and date range filters
+		filters, err := parseLogFilters(r.URL.Query(), vocabulary)
+		if err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+			return
 		}
 
-		var logs []Log
-		for rows.Next() {
-			var (
-				logEntry    Log
-				notes       sql.NullString
-				startedAt   sql.NullTime
-				completedAt sql.NullTime
-			)
-
-			err := rows.Scan(
-				&logEntry.LogId,
-				&logEntry.TaskName,
-				&logEntry.TaskType,
-				&logEntry.TaskStatus,
-				&notes,
-				&startedAt,
-				&completedAt,
-				&logEntry.CreatedAt,
-				&logEntry.UpdatedAt,
-				&logEntry.Priority,
-				&logEntry.TotalPages,
-			)
+		if !validateProjectScope(store, w, filters) {
+			return
+		}
 
-			if err != nil {
-				http.Error(w, "Error scanning row: "+err.Error(), http.StatusInternalServerError)
-			}
+		options := GetAllLogsOpts{LogFilters: filters, s: searchVal, sortBy: sortBy, sortOrder: sortOrder, limit: limit, page: page}
+		if _, _, err := options.orderBy(); err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+			return
+		}
 
-			if notes.Valid {
-				logEntry.Notes = notes.String
-			} else {
-				logEntry.Notes = "n/a"
+		// cursor mode, an empty cursor asks for the first page
+		if r.URL.Query().Has("cursor") {
+			options.cursorMode = true
+			if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+				options.cursor, err = decodeCursor(cursor)
+				if err != nil {
+					w.WriteHeader(http.StatusUnprocessableEntity)
+					json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+					return
+				}
 			}
 
-			if completedAt.Valid {
-				logEntry.CompletedAt = &completedAt.Time
-			} else {
-				logEntry.CompletedAt = nil
+			if err := options.validateCursor(); err != nil {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+				return
 			}
+		}
 
-			if startedAt.Valid {
-				logEntry.StartedAt = &startedAt.Time
-			} else {
-				logEntry.StartedAt = nil
-			}
+		// lexical search
+		// for task_name, task_type, task_status, notes
+		logsPage, err := store.GetAllLogs(options)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 
-			logs = append(logs, logEntry)
+		type Log struct {
+			WorkLog
+			TotalPages int `json:"totalPages,omitempty"`
 		}
 
-		if logs == nil {
-			json.NewEncoder(w).Encode(struct {
-				Logs []Log `json:"logs"`
-			}{Logs: []Log{}})
-			return
+		response := []Log{}
+		for _, logEntry := range logsPage.Logs {
+			response = append(response, Log{WorkLog: logEntry, TotalPages: logsPage.TotalPages})
 		}
 
 		json.NewEncoder(w).Encode(struct {
-			Logs []Log `json:"logs"`
-		}{Logs: logs})
+			Logs       []Log  `json:"logs"`
+			NextCursor string `json:"nextCursor,omitempty"`
+		}{Logs: response, NextCursor: logsPage.NextCursor})
 	})
 
 	mux.HandleFunc("/log/{logId}", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		logId := r.PathValue("logId")
-		worklog, err := getLogById(db, logId)
+		worklog, err := store.GetLogById(logId)
 		if err != nil {
-			if err == sql.ErrNoRows {
+			if err == ErrLogNotFound {
 				w.WriteHeader(http.StatusNotFound)
 				json.NewEncoder(w).Encode(map[string]string{"message": "No records found"})
 				return
@@ -733,48 +678,113 @@ func main() {
 			return
 		}
 
+		links, err := store.GetLogLinks(logId)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong"})
+			return
+		}
+
 		w.WriteHeader(http.StatusOK)
 		type response struct {
-			Message string  `json:"message"`
-			Log     WorkLog `json:"log"`
+			Message string   `json:"message"`
+			Log     WorkLog  `json:"log"`
+			Links   LogLinks `json:"links"`
 		}
 
-		json.NewEncoder(w).Encode(&response{Message: "Ok", Log: worklog})
+		json.NewEncoder(w).Encode(&response{Message: "Ok", Log: worklog, Links: links})
+	})
+
+	mux.HandleFunc("GET /log/{logId}/tree", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		tree, err := store.GetLogTree(r.PathValue("logId"))
+		if err != nil {
+			if err == ErrLogNotFound {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"message": "No records found"})
+				return
+			}
+
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong"})
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(struct {
+			Message string  `json:"message"`
+			Log     LogTree `json:"log"`
+		}{Message: "Ok", Log: tree})
+	})
+
+	mux.HandleFunc("GET /log/{logId}/history", func(w http.ResponseWriter, r *http.Request) {
+		handleGetLogHistory(store, w, r)
+	})
+
+	mux.HandleFunc("POST /log/{logId}/timer/start", func(w http.ResponseWriter, r *http.Request) {
+		handleStartTimer(store, w, r)
+	})
+
+	mux.HandleFunc("POST /log/{logId}/timer/stop", func(w http.ResponseWriter, r *http.Request) {
+		handleStopTimer(store, w, r)
+	})
+
+	mux.HandleFunc("GET /log/{logId}/time-entries", func(w http.ResponseWriter, r *http.Request) {
+		handleGetTimeEntries(store, w, r)
+	})
+
+	mux.HandleFunc("POST /log/{logId}/time-entries", func(w http.ResponseWriter, r *http.Request) {
+		handleCreateTimeEntry(store, w, r)
+	})
+
+	mux.HandleFunc("PUT /time-entries/{entryId}", func(w http.ResponseWriter, r *http.Request) {
+		handleUpdateTimeEntry(store, w, r)
+	})
+
+	mux.HandleFunc("DELETE /time-entries/{entryId}", func(w http.ResponseWriter, r *http.Request) {
+		handleDeleteTimeEntry(store, w, r)
+	})
+
+	mux.HandleFunc("GET /activity", func(w http.ResponseWriter, r *http.Request) {
+		handleGetActivity(store, w, r)
+	})
+
+	mux.HandleFunc("POST /log/{logId}/blocks/{blockedLogId}", func(w http.ResponseWriter, r *http.Request) {
+		handleAddLogLink(store, w, r)
+	})
+
+	mux.HandleFunc("DELETE /log/{logId}/blocks/{blockedLogId}", func(w http.ResponseWriter, r *http.Request) {
+		handleRemoveLogLink(store, w, r)
 	})
 
 	mux.HandleFunc("PUT /log", func(w http.ResponseWriter, r *http.Request) {
-		updateLog(w, r, db)
+		updateLog(w, r, store, workflow)
 	})
 
 	mux.HandleFunc("POST /log", func(w http.ResponseWriter, r *http.Request) {
-		handleCreateLog(db, w, r)
+		handleCreateLog(store, workflow, w, r)
 	})
 
 	mux.HandleFunc("DELETE /log/{logId}", func(w http.ResponseWriter, r *http.Request) {
 		logId := r.PathValue("logId")
 		w.Header().Set("Content-Type", "application/json")
 
-		// validate log id
-		q := "select log_id from logs where log_id = $1"
-		var id string
-		err := db.QueryRow(q, logId).Scan(&id)
+		cascade, ok := parseChildrenRule(r.URL.Query().Get("children"))
+		if !ok {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Invalid children value, expected reparent or delete"})
+			return
+		}
+
+		err := store.DeleteLog(logId, cascade)
 		if err != nil {
-			if err == sql.ErrNoRows {
+			if err == ErrLogNotFound {
 				w.WriteHeader(http.StatusNotFound)
 				json.NewEncoder(w).Encode(map[string]string{"message": "No log found"})
 				return
 			}
 
-			json.NewEncoder(w).Encode(struct {
-				Message string `json:"message"`
-			}{Message: err.Error()})
-			return
-		}
-
-		// delete the log by log id
-		q = "delete from logs where log_id = $1"
-		_, err = db.Exec(q, logId)
-		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(struct {
 				Message string `json:"message"`
 			}{Message: err.Error()})
@@ -803,21 +813,14 @@ func main() {
 			return
 		}
 
-		// Process the decoded log IDs
-		// delete from logs where log_id in (a, b, c, d, ..., n)
-		for i, id := range ids {
-			ids[i] = fmt.Sprintf("'%s'", id)
-		}
-
-		q := fmt.Sprintf("delete from logs where log_id in (%s)", strings.Join(ids, ", "))
-		result, err := db.Exec(q)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while deleting logs."})
+		cascade, ok := parseChildrenRule(r.URL.Query().Get("children"))
+		if !ok {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Invalid children value, expected reparent or delete"})
 			return
 		}
 
-		rowCount, err := result.RowsAffected()
+		rowCount, err := store.DeleteLogs(ids, cascade)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while deleting logs."})
@@ -833,195 +836,92 @@ func main() {
 
 	mux.HandleFunc("GET /status-summary", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		q := `
-			SELECT
-				TASK_STATUS,
-				COUNT(TASK_STATUS) AS STATUS_COUNT,
-				(
-					COUNT(TASK_STATUS)::FLOAT / (
-						SELECT
-							COUNT(*)
-						FROM
-							LOGS
-					)
-				) * 100 AS PERCENTAGE
-			FROM
-				LOGS
-			GROUP BY
-				TASK_STATUS;
-		`
-
-		fmt.Println("[query]: ", q)
-		rows, err := db.Query(q)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't worng while fetching status summary."})
+		filters := LogFilters{projectId: strings.TrimSpace(r.URL.Query().Get("projectId"))}
+		if !validateProjectScope(store, w, filters) {
 			return
 		}
 
-		defer rows.Close()
-		type Summary struct {
-			TaskStatus  string  `json:"taskStatus"`
-			StatusCount int     `json:"statusCount"`
-			Percentage  float64 `json:"percentage"`
-		}
-
-		var summary []Summary
-		for rows.Next() {
-			var row Summary
-			err := rows.Scan(
-				&row.TaskStatus,
-				&row.StatusCount,
-				&row.Percentage,
-			)
-
-			if err != nil {
-				http.Error(w, "Error scanning row: "+err.Error(), http.StatusInternalServerError)
-				return
-			}
-
-			summary = append(summary, row)
+		vocabulary, ok := loadVocabulary(store, w)
+		if !ok {
+			return
 		}
 
-		if summary == nil {
-			json.NewEncoder(w).Encode(struct {
-				Summary []Summary `json:"statusSummary"`
-			}{Summary: []Summary{}})
+		summary, err := store.GetStatusSummary(filters)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't worng while fetching status summary."})
 			return
 		}
 
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(struct {
-			Summary []Summary `json:"statusSummary"`
-		}{Summary: summary})
+			Summary []StatusSummary `json:"statusSummary"`
+		}{Summary: completeStatusSummary(summary, vocabulary)})
 	})
 
 	mux.HandleFunc("GET /type-summary", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		q := `
-			SELECT
-				TASK_TYPE,
-				COUNT(TASK_TYPE) AS TYPE_COUNT,
-				(
-					COUNT(TASK_TYPE)::FLOAT / (
-						SELECT
-							COUNT(*)
-						FROM
-							LOGS
-					)
-				) * 100 AS PERCENTAGE
-			FROM
-				LOGS
-			GROUP BY
-				TASK_TYPE;
-		`
-
-		fmt.Println("[query]: ", q)
-		rows, err := db.Query(q)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't worng while fetching task type summary."})
+		filters := LogFilters{projectId: strings.TrimSpace(r.URL.Query().Get("projectId"))}
+		if !validateProjectScope(store, w, filters) {
 			return
 		}
 
-		defer rows.Close()
-		type Summary struct {
-			TaskType    string  `json:"taskType"`
-			StatusCount int     `json:"statusCount"`
-			Percentage  float64 `json:"percentage"`
+		vocabulary, ok := loadVocabulary(store, w)
+		if !ok {
+			return
 		}
 
-		var summary []Summary
-		for rows.Next() {
-			var row Summary
-			err := rows.Scan(
-				&row.TaskType,
-				&row.StatusCount,
-				&row.Percentage,
-			)
+		summary, err := store.GetTypeSummary(filters)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't worng while fetching task type summary."})
+			return
+		}
 
-			if err != nil {
-				http.Error(w, "Error scanning row: "+err.Error(), http.StatusInternalServerError)
-				return
-			}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(struct {
+			Summary []TypeSummary `json:"typeSummary"`
+		}{Summary: completeTypeSummary(summary, vocabulary)})
+	})
 
-			summary = append(summary, row)
+	mux.HandleFunc("GET /tag-summary", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		filters := LogFilters{projectId: strings.TrimSpace(r.URL.Query().Get("projectId"))}
+		if !validateProjectScope(store, w, filters) {
+			return
 		}
 
-		if summary == nil {
-			json.NewEncoder(w).Encode(struct {
-				Summary []Summary `json:"typeSummary"`
-			}{Summary: []Summary{}})
+		summary, err := store.GetTagSummary(filters)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't worng while fetching tag summary."})
 			return
 		}
 
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(struct {
-			Summary []Summary `json:"typeSummary"`
+			Summary []TagSummary `json:"tagSummary"`
 		}{Summary: summary})
 	})
 
 	mux.HandleFunc("GET /daily-task-count", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		q := `
-			SELECT
-				CREATED_AT::DATE AS CREATED_DATE,
-				TO_CHAR(CREATED_AT::DATE, 'DD MON YYYY') AS FORMATTED_DATE,
-				COUNT(*) AS TASK_COUNT
-			FROM
-				LOGS
-			GROUP BY
-				CREATED_DATE
-			ORDER BY
-				CREATED_DATE;
-		`
-		// Print the query
-		fmt.Println("[query]: ", q)
-
-		// Execute the query
-		rows, err := db.Query(q)
-
-		// Error handling
+		location, err := parseTimezone(r.URL.Query())
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while fetching daily task count"})
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
 			return
 		}
 
-		// Close the rows
-		defer rows.Close()
-
-		// define the response structure
-		type DailyTask struct {
-			CreatedDate   time.Time `json:"createdDate"`
-			FormattedDate string    `json:"formattedDate"`
-			TaskCount     int       `json:"taskCount"`
-		}
-
-		dailyTasks := []DailyTask{}
-
-		// Scan the rows
-		for rows.Next() {
-			var row DailyTask
-			err := rows.Scan(
-				&row.CreatedDate,
-				&row.FormattedDate,
-				&row.TaskCount,
-			)
-
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while fetching daily task count"})
-				return
-			}
-
-			dailyTasks = append(dailyTasks, row)
+		filters := LogFilters{projectId: strings.TrimSpace(r.URL.Query().Get("projectId"))}
+		if !validateProjectScope(store, w, filters) {
+			return
 		}
 
-		if len(dailyTasks) == 0 {
-			json.NewEncoder(w).Encode(struct {
-				DailyTasks []DailyTask `json:"dailyTasks"`
-			}{DailyTasks: []DailyTask{}})
+		dailyTasks, err := store.GetDailyTaskCount(filters, location)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while fetching daily task count"})
 			return
 		}
 
@@ -1032,133 +932,119 @@ func main() {
 	})
 
 	mux.HandleFunc("GET /completed-task-count", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		view := r.URL.Query().Get("v")
-		duration := r.URL.Query().Get("d")
+		handleGetCompletedTaskCount(store, w, r)
+	})
 
-		if view == "" {
-			view = "week"
-		}
+	mux.HandleFunc("GET /reports/timesheet", func(w http.ResponseWriter, r *http.Request) {
+		handleGetTimesheet(store, w, r)
+	})
 
-		if duration == "" {
-			duration = "1 months"
-		}
+	mux.HandleFunc("GET /task-summary", func(w http.ResponseWriter, r *http.Request) {
+		handleGetTaskSummary(store, w, r)
+	})
 
-		var interval string
-		if view == "week" {
-			interval = "1 week"
-		} else {
-			interval = "1 month"
-		}
+	mux.HandleFunc("GET /projects", func(w http.ResponseWriter, r *http.Request) {
+		handleGetProjects(store, w, r)
+	})
 
-		q := fmt.Sprintf(`
-			WITH
-				DATE_SERIES AS (
-					SELECT
-						GENERATE_SERIES(
-							DATE_TRUNC('%s', NOW() - INTERVAL '%s'),
-							DATE_TRUNC('%s', NOW()),
-							INTERVAL '%s'
-						) AS DATE_START
-				),
-				LOGS_BY_VIEW AS (
-					SELECT
-						DATE_TRUNC('%s', COMPLETED_AT) AS DATE_START,
-						COUNT(TASK_NAME) AS TASK_COUNT
-					FROM
-						LOGS
-					GROUP BY
-						DATE_START
-				)
-			SELECT
-				D.DATE_START,
-				COALESCE(L.TASK_COUNT, 0) AS TASK_COUNT
-			FROM
-				DATE_SERIES D
-				LEFT JOIN LOGS_BY_VIEW L ON D.DATE_START = L.DATE_START
-			ORDER BY
-				DATE_START;
-		`, view, duration, view, interval, view)
-
-		fmt.Println("[query]: ", q)
-
-		rows, err := db.Query(q)
-		if err != nil {
-			fmt.Println(err.Error())
-			http.Error(w, "Something wen't wrong while getting completed task count", http.StatusInternalServerError)
-			return
-		}
+	mux.HandleFunc("GET /project/{projectId}", func(w http.ResponseWriter, r *http.Request) {
+		handleGetProject(store, w, r)
+	})
 
-		defer rows.Close()
+	mux.HandleFunc("POST /project", func(w http.ResponseWriter, r *http.Request) {
+		handleCreateProject(store, w, r)
+	})
 
-		type CompletedCount struct {
-			CompletedAt time.Time `json:"completedAt"`
-			TaskCount   int       `json:"taskCount"`
-		}
+	mux.HandleFunc("PUT /project", func(w http.ResponseWriter, r *http.Request) {
+		handleUpdateProject(store, w, r)
+	})
 
-		var completedCounts []CompletedCount
-		for rows.Next() {
-			var completedCount CompletedCount
-			rows.Scan(
-				&completedCount.CompletedAt,
-				&completedCount.TaskCount,
-			)
+	mux.HandleFunc("DELETE /project/{projectId}", func(w http.ResponseWriter, r *http.Request) {
+		handleDeleteProject(store, w, r)
+	})
 
-			completedCounts = append(completedCounts, completedCount)
-		}
+	mux.HandleFunc("GET /meta", func(w http.ResponseWriter, r *http.Request) {
+		handleGetMeta(store, w, r)
+	})
 
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(struct {
-			Message        string           `json:"message"`
-			CompletedCount []CompletedCount `json:"completedCount"`
-		}{Message: "ok", CompletedCount: completedCounts})
+	mux.HandleFunc("PUT /meta/task-types/{name}", func(w http.ResponseWriter, r *http.Request) {
+		handleSaveTerm(store, workflow, "task-types", w, r)
 	})
 
-	mux.HandleFunc("GET /task-summary", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		q := `
-			SELECT
-				COUNT(*) AS TOTAL_TASKS,
-				(
-					SELECT
-						COUNT(*)
-					FROM
-						LOGS
-					WHERE
-						TASK_TYPE = 'bug'
-				) AS TOTAL_BUGS,
-				(
-					SELECT
-						COUNT(*)
-					FROM
-						LOGS
-					WHERE
-						TASK_STATUS = 'progress'
-				) AS TOTAL_PROGRESS_TASKS,
-				(
-					SELECT
-						COUNT(*)
-					FROM
-						LOGS
-					WHERE
-						PRIORITY = 10
-				) AS HIGHEST_PRIORITY_TASKS
-			FROM
-				LOGS;
-		`
-
-		rows, err := db.Query(q)
-		if err != nil {
-			http.Error(w, "Something wen't wrong while generate summary", http.StatusInternalServerError)
-			return
-		}
+	mux.HandleFunc("DELETE /meta/task-types/{name}", func(w http.ResponseWriter, r *http.Request) {
+		handleDeleteTerm(store, workflow, "task-types", w, r)
+	})
 
-		defer rows.Close()
-		for rows.Next() {
+	mux.HandleFunc("PUT /meta/task-statuses/{name}", func(w http.ResponseWriter, r *http.Request) {
+		handleSaveTerm(store, workflow, "task-statuses", w, r)
+	})
 
-		}
+	mux.HandleFunc("DELETE /meta/task-statuses/{name}", func(w http.ResponseWriter, r *http.Request) {
+		handleDeleteTerm(store, workflow, "task-statuses", w, r)
+	})
 
-		w.WriteHeader(http.StatusOK)
+	mux.HandleFunc("PUT /meta/priorities/{value}", func(w http.ResponseWriter, r *http.Request) {
+		handleSavePriority(store, w, r)
+	})
+
+	mux.HandleFunc("DELETE /meta/priorities/{value}", func(w http.ResponseWriter, r *http.Request) {
+		handleDeletePriority(store, w, r)
+	})
+
+	mux.HandleFunc("GET /workflow", func(w http.ResponseWriter, r *http.Request) {
+		handleGetWorkflow(workflow, w, r)
+	})
+
+	mux.HandleFunc("GET /analytics/status-durations", func(w http.ResponseWriter, r *http.Request) {
+		handleGetStatusDurations(store, w, r)
+	})
+
+	mux.HandleFunc("GET /analytics/flow-times", func(w http.ResponseWriter, r *http.Request) {
+		handleGetFlowTimes(store, w, r)
+	})
+
+	mux.HandleFunc("GET /analytics/cfd", func(w http.ResponseWriter, r *http.Request) {
+		handleGetCumulativeFlow(store, w, r)
+	})
+
+	mux.HandleFunc("GET /analytics/forecast", func(w http.ResponseWriter, r *http.Request) {
+		handleGetForecast(store, w, r)
+	})
+
+	mux.HandleFunc("GET /iterations", func(w http.ResponseWriter, r *http.Request) {
+		handleGetIterations(store, w, r)
+	})
+
+	mux.HandleFunc("POST /iteration", func(w http.ResponseWriter, r *http.Request) {
+		handleCreateIteration(store, w, r)
+	})
+
+	mux.HandleFunc("GET /iteration/{iterationId}", func(w http.ResponseWriter, r *http.Request) {
+		handleGetIteration(store, w, r)
+	})
+
+	mux.HandleFunc("PUT /iteration/{iterationId}", func(w http.ResponseWriter, r *http.Request) {
+		handleUpdateIteration(store, w, r)
+	})
+
+	mux.HandleFunc("DELETE /iteration/{iterationId}", func(w http.ResponseWriter, r *http.Request) {
+		handleDeleteIteration(store, w, r)
+	})
+
+	mux.HandleFunc("POST /iteration/{iterationId}/logs", func(w http.ResponseWriter, r *http.Request) {
+		handleAddIterationLog(store, w, r)
+	})
+
+	mux.HandleFunc("DELETE /iteration/{iterationId}/logs/{logId}", func(w http.ResponseWriter, r *http.Request) {
+		handleRemoveIterationLog(store, w, r)
+	})
+
+	mux.HandleFunc("GET /iteration/{iterationId}/burndown", func(w http.ResponseWriter, r *http.Request) {
+		handleGetIterationBurndown(store, w, r)
+	})
+
+	mux.HandleFunc("GET /insights/stale", func(w http.ResponseWriter, r *http.Request) {
+		handleGetStaleLogs(store, w, r)
 	})
 
 	if err := http.ListenAndServe(":"+serverPort, corsMiddleware(mux)); err != nil {
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// numbered sql migrations for every driver, named
+// migrations/<driver>/<version>_<name>.(up|down).sql
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+type migrationStatus struct {
+	version   int
+	name      string
+	appliedAt *time.Time
+}
+
+// tracking table, one row per applied migration
+var migrationTables = map[string]string{
+	"postgres": `create table if not exists schema_migrations (
+		version integer primary key,
+		name text not null,
+		applied_at timestamptz not null default now()
+	)`,
+	"sqlite": `create table if not exists schema_migrations (
+		version integer primary key,
+		name text not null,
+		applied_at datetime not null default (` + sqliteNow + `)
+	)`,
+}
+
+// read the embedded migrations of a driver, sorted by version
+func loadMigrations(driver string) ([]migration, error) {
+	dir := path.Join("migrations", driver)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*migration{}
+	for _, entry := range entries {
+		fileName := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		versionStr, name, found := strings.Cut(base, "_")
+		version, err := strconv.Atoi(versionStr)
+		if !found || err != nil {
+			return nil, fmt.Errorf("invalid migration file name %s", fileName)
+		}
+
+		content, err := fs.ReadFile(migrationFiles, path.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		}
+
+		if direction == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+	}
+
+	migrations := []migration{}
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.version, m.name)
+		}
+
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+// versions already applied on the database, with the time they were applied
+func appliedMigrations(db *sql.DB, driver string) (map[int]time.Time, error) {
+	if _, err := db.Exec(migrationTables[driver]); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("select version, applied_at from schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// run a migration script and update the tracking table in one transaction
+func runMigration(db *sql.DB, script string, record string, args ...any) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(script); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.Exec(record, args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// apply every pending migration
+func MigrateUp(db *sql.DB, driver string) error {
+	migrations, err := loadMigrations(driver)
+	if err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(db, driver)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+
+		log.Printf("applying migration %04d_%s", m.version, m.name)
+		err := runMigration(db, m.up, "insert into schema_migrations (version, name) values ($1, $2)", m.version, m.name)
+		if err != nil {
+			return fmt.Errorf("migration %04d_%s: %w", m.version, m.name, err)
+		}
+	}
+
+	return nil
+}
+
+// revert the last n applied migrations
+func MigrateDown(db *sql.DB, driver string, steps int) error {
+	migrations, err := loadMigrations(driver)
+	if err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(db, driver)
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.version]; !ok {
+			continue
+		}
+
+		if m.down == "" {
+			return fmt.Errorf("migration %04d_%s has no down file", m.version, m.name)
+		}
+
+		log.Printf("reverting migration %04d_%s", m.version, m.name)
+		err := runMigration(db, m.down, "delete from schema_migrations where version = $1", m.version)
+		if err != nil {
+			return fmt.Errorf("migration %04d_%s: %w", m.version, m.name, err)
+		}
+
+		steps--
+	}
+
+	return nil
+}
+
+// every known migration and when it was applied, nil if it is pending
+func GetMigrationStatus(db *sql.DB, driver string) ([]migrationStatus, error) {
+	migrations, err := loadMigrations(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(db, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	status := []migrationStatus{}
+	for _, m := range migrations {
+		row := migrationStatus{version: m.version, name: m.name}
+		if appliedAt, ok := applied[m.version]; ok {
+			row.appliedAt = &appliedAt
+		}
+
+		status = append(status, row)
+	}
+
+	return status, nil
+}
+
+// migrate up | down [steps] | status
+func runMigrateCommand(args []string) {
+	secrets := GetSecrets()
+	var db *sql.DB
+	switch secrets.dbDriver {
+	case "postgres":
+		db = OpenPostgres()
+	case "sqlite":
+		db = OpenSQLite(secrets.sqlitePath)
+	default:
+		log.Fatal("unsupported DB_DRIVER " + secrets.dbDriver)
+	}
+
+	defer db.Close()
+	command := "status"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		if err := MigrateUp(db, secrets.dbDriver); err != nil {
+			log.Fatal(err)
+		}
+
+		log.Println("migrations are up to date")
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				log.Fatal("invalid number of steps " + args[1])
+			}
+
+			steps = n
+		}
+
+		if err := MigrateDown(db, secrets.dbDriver, steps); err != nil {
+			log.Fatal(err)
+		}
+	case "status":
+		status, err := GetMigrationStatus(db, secrets.dbDriver)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, row := range status {
+			state := "pending"
+			if row.appliedAt != nil {
+				state = "applied " + row.appliedAt.Format(time.RFC3339)
+			}
+
+			fmt.Printf("%04d_%-40s %s\n", row.version, row.name, state)
+		}
+	default:
+		log.Fatal("usage: worklog migrate up | down [steps] | status")
+	}
+}
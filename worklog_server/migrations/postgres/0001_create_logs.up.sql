@@ -0,0 +1,24 @@
+-- search extensions used by /logs
+create extension if not exists pg_trgm;
+create extension if not exists fuzzystrmatch;
+create extension if not exists pgcrypto;
+
+create table if not exists logs (
+    log_id uuid primary key default gen_random_uuid(),
+    task_name varchar(255) not null,
+    task_type varchar(50) not null,
+    task_status varchar(50) not null,
+    priority integer not null default 1,
+    notes text,
+    started_at timestamptz,
+    completed_at timestamptz,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    -- search vector for full text search
+    ts tsvector generated always as (
+        to_tsvector('english', task_name || ' ' || coalesce(notes, ''))
+    ) stored
+);
+
+create index if not exists logs_ts_idx on logs using gin (ts);
+create index if not exists logs_updated_at_idx on logs (updated_at);
@@ -0,0 +1,10 @@
+alter table logs drop column ts;
+alter table logs drop column tag_names;
+alter table logs add column ts tsvector generated always as (
+    to_tsvector('english', task_name || ' ' || coalesce(notes, ''))
+) stored;
+
+create index if not exists logs_ts_idx on logs using gin (ts);
+
+drop table if exists log_tags;
+drop table if exists tags;
@@ -0,0 +1,23 @@
+create table if not exists tags (
+    name varchar(50) primary key,
+    created_at timestamptz not null default now()
+);
+
+create table if not exists log_tags (
+    log_id uuid not null references logs (log_id) on delete cascade,
+    tag_name varchar(50) not null references tags (name) on delete cascade,
+    primary key (log_id, tag_name)
+);
+
+create index if not exists log_tags_tag_name_idx on log_tags (tag_name);
+
+-- space separated tag names of the log, kept in sync by the store so the
+-- search vector can match them
+alter table logs add column tag_names text not null default '';
+
+alter table logs drop column ts;
+alter table logs add column ts tsvector generated always as (
+    to_tsvector('english', task_name || ' ' || coalesce(notes, '') || ' ' || tag_names)
+) stored;
+
+create index if not exists logs_ts_idx on logs using gin (ts);
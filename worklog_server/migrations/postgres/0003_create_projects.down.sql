@@ -0,0 +1,3 @@
+drop index if exists logs_project_task_name_idx;
+alter table logs drop column project_id;
+drop table if exists projects;
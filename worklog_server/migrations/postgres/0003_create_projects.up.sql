@@ -0,0 +1,23 @@
+create table if not exists projects (
+    project_id uuid primary key default gen_random_uuid(),
+    name varchar(255) not null unique,
+    description text not null default '',
+    is_default boolean not null default false,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+
+-- logs created without a project go to the default one
+create unique index if not exists projects_is_default_idx on projects (is_default) where is_default;
+
+insert into projects (name, description, is_default)
+values ('Default', 'Logs without a project', true);
+
+alter table logs add column project_id uuid references projects (project_id);
+update logs set project_id = (select project_id from projects where is_default);
+alter table logs alter column project_id set not null;
+
+create index if not exists logs_project_id_idx on logs (project_id);
+
+-- task names are unique per project
+create unique index if not exists logs_project_task_name_idx on logs (project_id, task_name);
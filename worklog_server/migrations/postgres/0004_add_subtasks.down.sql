@@ -0,0 +1,2 @@
+drop index if exists logs_parent_log_id_idx;
+alter table logs drop column parent_log_id;
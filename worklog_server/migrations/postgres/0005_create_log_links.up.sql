@@ -0,0 +1,10 @@
+-- blocker_log_id blocks blocked_log_id until it is completed
+create table if not exists log_links (
+    blocker_log_id uuid not null references logs (log_id) on delete cascade,
+    blocked_log_id uuid not null references logs (log_id) on delete cascade,
+    created_at timestamptz not null default now(),
+    primary key (blocker_log_id, blocked_log_id),
+    check (blocker_log_id <> blocked_log_id)
+);
+
+create index if not exists log_links_blocked_log_id_idx on log_links (blocked_log_id);
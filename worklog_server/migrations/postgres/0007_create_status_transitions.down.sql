@@ -0,0 +1 @@
+drop table if exists log_status_transitions;
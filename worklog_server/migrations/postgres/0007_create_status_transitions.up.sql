@@ -0,0 +1,38 @@
+-- every status a log went through, from_status is null for the first one
+create table if not exists log_status_transitions (
+    transition_id bigint generated always as identity primary key,
+    log_id uuid not null references logs (log_id) on delete cascade,
+    from_status varchar(50),
+    to_status varchar(50) not null,
+    transitioned_at timestamptz not null default now()
+);
+
+create index if not exists log_status_transitions_log_id_idx on log_status_transitions (log_id, transitioned_at);
+
+-- status changes already in the history
+insert into log_status_transitions (log_id, from_status, to_status, transitioned_at)
+select log_events.log_id, log_changes.old_value, log_changes.new_value, log_events.changed_at
+from log_events
+join log_changes on log_changes.event_id = log_events.event_id
+join logs on logs.log_id = log_events.log_id
+where log_changes.field = 'taskStatus' and log_events.action in ('create', 'update')
+order by log_events.event_id;
+
+-- logs older than the history start in the status they had back then
+insert into log_status_transitions (log_id, from_status, to_status, transitioned_at)
+select
+    logs.log_id,
+    null,
+    coalesce((
+        select log_changes.old_value
+        from log_events
+        join log_changes on log_changes.event_id = log_events.event_id
+        where log_events.log_id = logs.log_id and log_changes.field = 'taskStatus'
+        order by log_events.event_id
+        limit 1
+    ), logs.task_status),
+    logs.created_at
+from logs
+where not exists (
+    select 1 from log_events where log_events.log_id = logs.log_id and log_events.action = 'create'
+);
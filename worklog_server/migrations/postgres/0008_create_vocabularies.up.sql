@@ -0,0 +1,37 @@
+-- allowed values of the task type, task status and priority of a log,
+-- listed by position
+create table if not exists task_types (
+    name varchar(50) primary key,
+    label varchar(50) not null,
+    position integer not null default 0
+);
+
+create table if not exists task_statuses (
+    name varchar(50) primary key,
+    label varchar(50) not null,
+    position integer not null default 0
+);
+
+create table if not exists priorities (
+    value integer primary key,
+    label varchar(50) not null,
+    position integer not null default 0
+);
+
+insert into task_types (name, label, position) values
+    ('task', 'Task', 1),
+    ('bug', 'Bug', 2),
+    ('story', 'Story', 3);
+
+insert into task_statuses (name, label, position) values
+    ('backlog', 'Backlog', 1),
+    ('pending', 'Pending', 2),
+    ('progress', 'Progress', 3),
+    ('pr', 'PR', 4),
+    ('staging', 'Staging', 5);
+
+insert into priorities (value, label, position) values
+    (1, 'Low', 1),
+    (5, 'Medium', 2),
+    (7, 'High', 3),
+    (10, 'Highest', 4);
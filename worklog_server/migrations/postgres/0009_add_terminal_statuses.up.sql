@@ -0,0 +1,6 @@
+-- a log in a terminal status is done, entering one stamps completed_at
+alter table task_statuses add column if not exists is_terminal boolean not null default false;
+
+insert into task_statuses (name, label, position, is_terminal)
+values ('done', 'Done', (select coalesce(max(position), 0) + 1 from task_statuses), true)
+on conflict (name) do update set is_terminal = true;
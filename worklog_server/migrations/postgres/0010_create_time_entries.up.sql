@@ -0,0 +1,19 @@
+-- sittings spent on a log, ended_at is null while the timer runs
+create table if not exists time_entries (
+    entry_id uuid primary key default gen_random_uuid(),
+    log_id uuid not null references logs (log_id) on delete cascade,
+    user_name varchar(100) not null,
+    started_at timestamptz not null default now(),
+    ended_at timestamptz,
+    notes text not null default '',
+    created_at timestamptz not null default now(),
+    check (ended_at is null or ended_at >= started_at)
+);
+
+create index if not exists time_entries_log_id_idx on time_entries (log_id, started_at);
+
+-- a user has one running timer at most
+create unique index if not exists time_entries_running_idx on time_entries (user_name) where ended_at is null;
+
+-- seconds of the finished entries of a log, kept up to date by the store
+alter table logs add column tracked_seconds bigint not null default 0;
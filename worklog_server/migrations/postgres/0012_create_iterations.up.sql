@@ -0,0 +1,23 @@
+-- date bounded iterations of a project, like two week sprints
+create table if not exists iterations (
+    iteration_id uuid primary key default gen_random_uuid(),
+    project_id uuid not null references projects (project_id) on delete cascade,
+    name varchar(255) not null,
+    starts_on date not null,
+    ends_on date not null,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    unique (project_id, name),
+    check (ends_on >= starts_on)
+);
+
+-- logs planned in an iteration, the estimate weights the burndown
+create table if not exists iteration_logs (
+    iteration_id uuid not null references iterations (iteration_id) on delete cascade,
+    log_id uuid not null references logs (log_id) on delete cascade,
+    estimate double precision check (estimate >= 0),
+    added_at timestamptz not null default now(),
+    primary key (iteration_id, log_id)
+);
+
+create index if not exists iteration_logs_log_id_idx on iteration_logs (log_id);
@@ -0,0 +1 @@
+alter table task_statuses drop column if exists is_started;
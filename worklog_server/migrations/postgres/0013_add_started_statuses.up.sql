@@ -0,0 +1,4 @@
+-- a log in a started status is being worked on, entering one stamps started_at
+alter table task_statuses add column if not exists is_started boolean not null default false;
+
+update task_statuses set is_started = true where name = 'progress';
@@ -0,0 +1,5 @@
+drop trigger if exists logs_fts_delete;
+drop trigger if exists logs_fts_update;
+drop trigger if exists logs_fts_insert;
+drop table if exists logs_fts;
+drop table if exists logs;
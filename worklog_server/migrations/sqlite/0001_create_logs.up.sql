@@ -0,0 +1,40 @@
+create table if not exists logs (
+    log_id text primary key default (lower(
+        hex(randomblob(4)) || '-' ||
+        hex(randomblob(2)) || '-4' ||
+        substr(hex(randomblob(2)), 2) || '-' ||
+        substr('89ab', 1 + (abs(random()) % 4), 1) ||
+        substr(hex(randomblob(2)), 2) || '-' ||
+        hex(randomblob(6))
+    )),
+    task_name text not null,
+    task_type text not null,
+    task_status text not null,
+    priority integer not null default 1,
+    notes text,
+    started_at datetime,
+    completed_at datetime,
+    created_at datetime not null default (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at datetime not null default (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+create index if not exists logs_updated_at_idx on logs (updated_at);
+
+-- full text index on the task name and notes, kept in sync with triggers
+create virtual table if not exists logs_fts using fts5(
+    log_id unindexed,
+    task_name,
+    notes
+);
+
+create trigger if not exists logs_fts_insert after insert on logs begin
+    insert into logs_fts (log_id, task_name, notes) values (new.log_id, new.task_name, new.notes);
+end;
+
+create trigger if not exists logs_fts_update after update of task_name, notes on logs begin
+    update logs_fts set task_name = new.task_name, notes = new.notes where log_id = old.log_id;
+end;
+
+create trigger if not exists logs_fts_delete after delete on logs begin
+    delete from logs_fts where log_id = old.log_id;
+end;
@@ -0,0 +1,29 @@
+drop trigger if exists logs_fts_insert;
+drop trigger if exists logs_fts_update;
+drop trigger if exists logs_fts_delete;
+drop table if exists logs_fts;
+
+create virtual table logs_fts using fts5(
+    log_id unindexed,
+    task_name,
+    notes
+);
+
+insert into logs_fts (log_id, task_name, notes)
+select log_id, task_name, notes from logs;
+
+create trigger logs_fts_insert after insert on logs begin
+    insert into logs_fts (log_id, task_name, notes) values (new.log_id, new.task_name, new.notes);
+end;
+
+create trigger logs_fts_update after update of task_name, notes on logs begin
+    update logs_fts set task_name = new.task_name, notes = new.notes where log_id = old.log_id;
+end;
+
+create trigger logs_fts_delete after delete on logs begin
+    delete from logs_fts where log_id = old.log_id;
+end;
+
+alter table logs drop column tag_names;
+drop table if exists log_tags;
+drop table if exists tags;
@@ -0,0 +1,46 @@
+create table if not exists tags (
+    name text primary key,
+    created_at datetime not null default (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+create table if not exists log_tags (
+    log_id text not null references logs (log_id) on delete cascade,
+    tag_name text not null references tags (name) on delete cascade,
+    primary key (log_id, tag_name)
+);
+
+create index if not exists log_tags_tag_name_idx on log_tags (tag_name);
+
+-- space separated tag names of the log, kept in sync by the store so the
+-- full text index can match them
+alter table logs add column tag_names text not null default '';
+
+drop trigger if exists logs_fts_insert;
+drop trigger if exists logs_fts_update;
+drop trigger if exists logs_fts_delete;
+drop table if exists logs_fts;
+
+create virtual table logs_fts using fts5(
+    log_id unindexed,
+    task_name,
+    notes,
+    tag_names
+);
+
+insert into logs_fts (log_id, task_name, notes, tag_names)
+select log_id, task_name, notes, tag_names from logs;
+
+create trigger logs_fts_insert after insert on logs begin
+    insert into logs_fts (log_id, task_name, notes, tag_names)
+    values (new.log_id, new.task_name, new.notes, new.tag_names);
+end;
+
+create trigger logs_fts_update after update of task_name, notes, tag_names on logs begin
+    update logs_fts
+    set task_name = new.task_name, notes = new.notes, tag_names = new.tag_names
+    where log_id = old.log_id;
+end;
+
+create trigger logs_fts_delete after delete on logs begin
+    delete from logs_fts where log_id = old.log_id;
+end;
@@ -0,0 +1,30 @@
+create table if not exists projects (
+    project_id text primary key default (lower(
+        hex(randomblob(4)) || '-' ||
+        hex(randomblob(2)) || '-4' ||
+        substr(hex(randomblob(2)), 2) || '-' ||
+        substr('89ab', 1 + (abs(random()) % 4), 1) ||
+        substr(hex(randomblob(2)), 2) || '-' ||
+        hex(randomblob(6))
+    )),
+    name text not null unique,
+    description text not null default '',
+    is_default boolean not null default false,
+    created_at datetime not null default (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at datetime not null default (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+-- logs created without a project go to the default one
+create unique index if not exists projects_is_default_idx on projects (is_default) where is_default;
+
+insert into projects (name, description, is_default)
+values ('Default', 'Logs without a project', true);
+
+-- sqlite can't add a not null column with a reference, the store always sets it
+alter table logs add column project_id text references projects (project_id);
+update logs set project_id = (select project_id from projects where is_default);
+
+create index if not exists logs_project_id_idx on logs (project_id);
+
+-- task names are unique per project
+create unique index if not exists logs_project_task_name_idx on logs (project_id, task_name);
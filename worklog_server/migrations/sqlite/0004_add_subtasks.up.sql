@@ -0,0 +1,5 @@
+-- subtasks point at their parent log, deleting a parent through the store
+-- re-parents or deletes the children first
+alter table logs add column parent_log_id text references logs (log_id) on delete set null;
+
+create index if not exists logs_parent_log_id_idx on logs (parent_log_id);
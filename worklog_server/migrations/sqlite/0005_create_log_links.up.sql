@@ -0,0 +1,10 @@
+-- blocker_log_id blocks blocked_log_id until it is completed
+create table if not exists log_links (
+    blocker_log_id text not null references logs (log_id) on delete cascade,
+    blocked_log_id text not null references logs (log_id) on delete cascade,
+    created_at datetime not null default (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    primary key (blocker_log_id, blocked_log_id),
+    check (blocker_log_id <> blocked_log_id)
+);
+
+create index if not exists log_links_blocked_log_id_idx on log_links (blocked_log_id);
@@ -0,0 +1,2 @@
+drop table if exists log_changes;
+drop table if exists log_events;
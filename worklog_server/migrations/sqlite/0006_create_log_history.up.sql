@@ -0,0 +1,23 @@
+-- every create, update and delete of a log. log_id has no reference so the
+-- history of deleted logs is kept
+create table if not exists log_events (
+    event_id integer primary key autoincrement,
+    log_id text not null,
+    action text not null check (action in ('create', 'update', 'delete')),
+    task_name text not null,
+    changed_at datetime not null default (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+create index if not exists log_events_log_id_idx on log_events (log_id, event_id);
+create index if not exists log_events_changed_at_idx on log_events (changed_at);
+
+-- old and new value of each field changed by an event
+create table if not exists log_changes (
+    event_id integer not null references log_events (event_id) on delete cascade,
+    field text not null,
+    old_value text,
+    new_value text,
+    primary key (event_id, field)
+);
+
+create index if not exists log_changes_field_idx on log_changes (field);
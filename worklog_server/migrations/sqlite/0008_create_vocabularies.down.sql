@@ -0,0 +1,3 @@
+drop table if exists priorities;
+drop table if exists task_statuses;
+drop table if exists task_types;
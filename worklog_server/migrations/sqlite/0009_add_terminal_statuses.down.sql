@@ -0,0 +1,2 @@
+delete from task_statuses where name = 'done' and not exists (select 1 from logs where task_status = 'done');
+alter table task_statuses drop column is_terminal;
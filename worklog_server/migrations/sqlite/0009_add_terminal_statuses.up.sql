@@ -0,0 +1,6 @@
+-- a log in a terminal status is done, entering one stamps completed_at
+alter table task_statuses add column is_terminal integer not null default 0;
+
+insert into task_statuses (name, label, position, is_terminal)
+values ('done', 'Done', (select coalesce(max(position), 0) + 1 from task_statuses), 1)
+on conflict (name) do update set is_terminal = 1;
@@ -0,0 +1,2 @@
+alter table logs drop column tracked_seconds;
+drop table if exists time_entries;
@@ -0,0 +1,26 @@
+-- sittings spent on a log, ended_at is null while the timer runs
+create table if not exists time_entries (
+    entry_id text primary key default (lower(
+        hex(randomblob(4)) || '-' ||
+        hex(randomblob(2)) || '-4' ||
+        substr(hex(randomblob(2)), 2) || '-' ||
+        substr('89ab', 1 + (abs(random()) % 4), 1) ||
+        substr(hex(randomblob(2)), 2) || '-' ||
+        hex(randomblob(6))
+    )),
+    log_id text not null references logs (log_id) on delete cascade,
+    user_name text not null,
+    started_at datetime not null default (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    ended_at datetime,
+    notes text not null default '',
+    created_at datetime not null default (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    check (ended_at is null or ended_at >= started_at)
+);
+
+create index if not exists time_entries_log_id_idx on time_entries (log_id, started_at);
+
+-- a user has one running timer at most
+create unique index if not exists time_entries_running_idx on time_entries (user_name) where ended_at is null;
+
+-- seconds of the finished entries of a log, kept up to date by the store
+alter table logs add column tracked_seconds integer not null default 0;
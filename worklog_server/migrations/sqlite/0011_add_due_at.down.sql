@@ -0,0 +1,2 @@
+drop index if exists logs_due_at_idx;
+alter table logs drop column due_at;
@@ -0,0 +1,4 @@
+-- a log past its due date and not in a terminal status is overdue
+alter table logs add column due_at datetime;
+
+create index if not exists logs_due_at_idx on logs (due_at);
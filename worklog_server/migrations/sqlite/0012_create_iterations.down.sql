@@ -0,0 +1,2 @@
+drop table if exists iteration_logs;
+drop table if exists iterations;
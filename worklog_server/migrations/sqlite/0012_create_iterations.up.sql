@@ -0,0 +1,30 @@
+-- date bounded iterations of a project, like two week sprints
+create table if not exists iterations (
+    iteration_id text primary key default (lower(
+        hex(randomblob(4)) || '-' ||
+        hex(randomblob(2)) || '-4' ||
+        substr(hex(randomblob(2)), 2) || '-' ||
+        substr('89ab', 1 + (abs(random()) % 4), 1) ||
+        substr(hex(randomblob(2)), 2) || '-' ||
+        hex(randomblob(6))
+    )),
+    project_id text not null references projects (project_id) on delete cascade,
+    name text not null,
+    starts_on date not null,
+    ends_on date not null,
+    created_at datetime not null default (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at datetime not null default (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    unique (project_id, name),
+    check (ends_on >= starts_on)
+);
+
+-- logs planned in an iteration, the estimate weights the burndown
+create table if not exists iteration_logs (
+    iteration_id text not null references iterations (iteration_id) on delete cascade,
+    log_id text not null references logs (log_id) on delete cascade,
+    estimate real check (estimate >= 0),
+    added_at datetime not null default (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    primary key (iteration_id, log_id)
+);
+
+create index if not exists iteration_logs_log_id_idx on iteration_logs (log_id);
@@ -0,0 +1 @@
+alter table task_statuses drop column is_started;
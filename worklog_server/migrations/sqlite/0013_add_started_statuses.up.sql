@@ -0,0 +1,4 @@
+-- a log in a started status is being worked on, entering one stamps started_at
+alter table task_statuses add column is_started integer not null default 0;
+
+update task_statuses set is_started = 1 where name = 'progress';
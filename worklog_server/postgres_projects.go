@@ -0,0 +1,121 @@
+package main
+
+import "database/sql"
+
+// check if a name is taken by another project
+func (s *PostgresStore) projectNameExists(name string, projectId string) (bool, error) {
+	q := "select 1 from projects where name = $1 and project_id::text <> $2"
+	var exists int
+	err := s.db.QueryRow(q, name, projectId).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (s *PostgresStore) CreateProject(input ProjectInput) (Project, error) {
+	exists, err := s.projectNameExists(input.Name, "")
+	if err != nil {
+		return Project{}, err
+	}
+
+	if exists {
+		return Project{}, ErrDuplicateProjectName
+	}
+
+	description := ""
+	if input.Description != nil {
+		description = *input.Description
+	}
+
+	q := "insert into projects (name, description) values ($1, $2) returning project_id"
+	var projectId string
+	if err := s.db.QueryRow(q, input.Name, description).Scan(&projectId); err != nil {
+		return Project{}, err
+	}
+
+	return s.GetProject(projectId)
+}
+
+func (s *PostgresStore) GetProject(projectId string) (Project, error) {
+	// anything but a uuid fails the cast in postgres
+	if !uuidPattern.MatchString(projectId) {
+		return Project{}, ErrProjectNotFound
+	}
+
+	q := "select " + projectColumns + " from projects where project_id = $1"
+	project, err := scanProject(s.db.QueryRow(q, projectId))
+	if err == sql.ErrNoRows {
+		return project, ErrProjectNotFound
+	}
+
+	return project, err
+}
+
+func (s *PostgresStore) GetProjects() ([]Project, error) {
+	q := "select " + projectColumns + " from projects order by is_default desc, name"
+	rows, err := s.db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	projects := []Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		projects = append(projects, project)
+	}
+
+	return projects, rows.Err()
+}
+
+func (s *PostgresStore) UpdateProject(projectId string, input ProjectInput) (Project, error) {
+	if _, err := s.GetProject(projectId); err != nil {
+		return Project{}, err
+	}
+
+	if input.Name != "" {
+		exists, err := s.projectNameExists(input.Name, projectId)
+		if err != nil {
+			return Project{}, err
+		}
+
+		if exists {
+			return Project{}, ErrDuplicateProjectName
+		}
+	}
+
+	q := `update projects set
+			name = coalesce(nullif($1, ''), name),
+			description = coalesce($2, description),
+			updated_at = now()
+		where project_id = $3`
+	if _, err := s.db.Exec(q, input.Name, input.Description, projectId); err != nil {
+		return Project{}, err
+	}
+
+	return s.GetProject(projectId)
+}
+
+func (s *PostgresStore) DeleteProject(projectId string) error {
+	project, err := s.GetProject(projectId)
+	if err != nil {
+		return err
+	}
+
+	if project.IsDefault {
+		return ErrDefaultProjectDeleted
+	}
+
+	if project.LogCount > 0 {
+		return ErrProjectNotEmpty
+	}
+
+	_, err = s.db.Exec("delete from projects where project_id = $1", projectId)
+	return err
+}
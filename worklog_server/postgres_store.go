@@ -0,0 +1,816 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// postgres implementation of the LogStore
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// keys of the advisory locks serialising the parent and the link writes,
+// the cycle checks then can't race
+const (
+	logParentsLockKey = 5450
+	logLinksLockKey   = 5451
+)
+
+// log writes shared with the sqlite store
+var postgresDialect = logDialect{now: "now()", args: func(args []any) []any { return args }}
+
+func (s *PostgresStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *PostgresStore) CreateLog(input CreateLogInput) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	// held until the end of the transaction
+	if input.ParentLogId != "" {
+		if _, err := tx.Exec("select pg_advisory_xact_lock($1)", logParentsLockKey); err != nil {
+			return err
+		}
+	}
+
+	if err := createLogTx(tx, postgresDialect, input); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *PostgresStore) GetLogById(logId string) (WorkLog, error) {
+	// validate log id
+	if !uuidPattern.MatchString(logId) {
+		return WorkLog{}, ErrLogNotFound
+	}
+
+	q := "select " + workLogColumns + " from logs where log_id = $1"
+	workLog, err := scanWorkLog(s.db.QueryRow(q, logId))
+	if err == sql.ErrNoRows {
+		return workLog, ErrLogNotFound
+	}
+
+	return workLog, err
+}
+
+func (s *PostgresStore) UpdateLog(logId string, input UpdateLogInput) (WorkLog, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return WorkLog{}, err
+	}
+
+	defer tx.Rollback()
+	// held until the end of the transaction
+	if input.ParentLogId != nil || input.ProjectId != "" {
+		if _, err := tx.Exec("select pg_advisory_xact_lock($1)", logParentsLockKey); err != nil {
+			return WorkLog{}, err
+		}
+	}
+
+	if err := updateLogTx(tx, postgresDialect, logId, input); err != nil {
+		return WorkLog{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return WorkLog{}, err
+	}
+
+	return s.GetLogById(logId)
+}
+
+// get a log with all of its subtasks
+func (s *PostgresStore) GetLogTree(logId string) (LogTree, error) {
+	root, err := s.GetLogById(logId)
+	if err != nil {
+		return LogTree{}, err
+	}
+
+	q := logDescendantsQuery + " select " + workLogColumns + " from logs where log_id in (select log_id from descendants) order by created_at, log_id"
+	rows, err := s.db.Query(q, logId)
+	if err != nil {
+		return LogTree{}, err
+	}
+
+	defer rows.Close()
+	descendants := []WorkLog{}
+	for rows.Next() {
+		workLog, err := scanWorkLog(rows)
+		if err != nil {
+			return LogTree{}, err
+		}
+
+		descendants = append(descendants, workLog)
+	}
+
+	if err := rows.Err(); err != nil {
+		return LogTree{}, err
+	}
+
+	return buildLogTree(root, descendants), nil
+}
+
+// delete a log, its subtasks are deleted too with cascade or moved up to
+// the parent of the log otherwise
+func (s *PostgresStore) DeleteLog(logId string, cascade bool) error {
+	// validate log id
+	if !uuidPattern.MatchString(logId) {
+		return ErrLogNotFound
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	if _, err := deleteLogTx(tx, logId, cascade, "now()"); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// delete logs, their subtasks are deleted too with cascade or moved up to
+// the closest ancestor that stays otherwise
+func (s *PostgresStore) DeleteLogs(logIds []string, cascade bool) (int64, error) {
+	// ids that aren't uuids can't match a log
+	validIds := []string{}
+	for _, logId := range logIds {
+		if uuidPattern.MatchString(logId) {
+			validIds = append(validIds, logId)
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+	rowCount, err := deleteLogsTx(tx, validIds, cascade, "now()")
+	if err != nil {
+		return 0, err
+	}
+
+	return rowCount, tx.Commit()
+}
+
+// get a page of the logs matching the filters
+func (s *PostgresStore) GetAllLogs(options GetAllLogsOpts) (LogsPage, error) {
+	sortBy, sortOrder, err := options.orderBy()
+	if err != nil {
+		return LogsPage{}, err
+	}
+
+	limit := options.pageLimit()
+	offset := 0
+	if options.page > 0 {
+		offset = options.page * limit
+	}
+
+	conditions, args := options.conditions(1)
+	from := "logs"
+	totalCount := "count(*) over()"
+	// sortBy and sortOrder come from the allowlist in orderBy
+	orderBy := fmt.Sprintf("logs.%s %s, logs.log_id", sortBy, sortOrder)
+
+	// ranked search on the task name and notes, the user's text is parsed by
+	// websearch_to_tsquery so quotes, "or" and "-" work like a search engine
+	if strings.TrimSpace(options.s) != "" {
+		args = append(args, options.s)
+		from = fmt.Sprintf("logs, websearch_to_tsquery('english', $%d) as query", len(args))
+		similarity := fmt.Sprintf("similarity($%d, task_name || ' ' || coalesce(notes, ''))", len(args))
+		conditions = append(conditions, fmt.Sprintf("(ts @@ query or %s > 0)", similarity))
+		orderBy = fmt.Sprintf("ts_rank(ts, query) desc, %s desc, logs.log_id", similarity)
+	}
+
+	// keyset pagination, search results are ordered by the sort column
+	// instead of the rank so the cursor stays stable
+	if options.cursorMode {
+		keyset, keysetArgs, err := options.keysetCondition(len(args) + 1)
+		if err != nil {
+			return LogsPage{}, err
+		}
+
+		conditions = append(conditions, keyset...)
+		args = append(args, keysetArgs...)
+		totalCount = "0"
+		orderBy = fmt.Sprintf("logs.%s %s, logs.log_id %s", sortBy, sortOrder, sortOrder)
+		offset = 0
+		// one more row to know if there is a next page
+		limit++
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "where " + strings.Join(conditions, " and ")
+	}
+
+	args = append(args, offset, limit)
+	q := fmt.Sprintf(`select
+			%s,
+			%s as total_count
+		from %s
+		%s
+		order by %s
+		offset $%d limit $%d`, workLogColumns, totalCount, from, where, orderBy, len(args)-1, len(args))
+
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return LogsPage{}, err
+	}
+
+	defer rows.Close()
+	logs := []WorkLog{}
+	count := 0
+	for rows.Next() {
+		logEntry, err := scanWorkLog(rows, &count)
+		if err != nil {
+			return LogsPage{}, err
+		}
+
+		logs = append(logs, logEntry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return LogsPage{}, err
+	}
+
+	if options.cursorMode {
+		return cursorPage(logs, options, limit-1)
+	}
+
+	totalPages := int(math.Ceil(float64(count) / float64(limit)))
+	return LogsPage{Logs: logs, TotalPages: totalPages}, nil
+}
+
+func (s *PostgresStore) GetStatusSummary(filters LogFilters) ([]StatusSummary, error) {
+	conditions, args := filters.conditions(1)
+	where := whereClause(conditions)
+	q := `
+		SELECT
+			TASK_STATUS,
+			COUNT(TASK_STATUS) AS STATUS_COUNT,
+			(
+				COUNT(TASK_STATUS)::FLOAT / (
+					SELECT
+						COUNT(*)
+					FROM
+						LOGS
+					` + where + `
+				)
+			) * 100 AS PERCENTAGE
+		FROM
+			LOGS
+		` + where + `
+		GROUP BY
+			TASK_STATUS;
+	`
+
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	summary := []StatusSummary{}
+	for rows.Next() {
+		var row StatusSummary
+		err := rows.Scan(
+			&row.TaskStatus,
+			&row.StatusCount,
+			&row.Percentage,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		summary = append(summary, row)
+	}
+
+	return summary, rows.Err()
+}
+
+func (s *PostgresStore) GetTypeSummary(filters LogFilters) ([]TypeSummary, error) {
+	conditions, args := filters.conditions(1)
+	where := whereClause(conditions)
+	q := `
+		SELECT
+			TASK_TYPE,
+			COUNT(TASK_TYPE) AS TYPE_COUNT,
+			(
+				COUNT(TASK_TYPE)::FLOAT / (
+					SELECT
+						COUNT(*)
+					FROM
+						LOGS
+					` + where + `
+				)
+			) * 100 AS PERCENTAGE
+		FROM
+			LOGS
+		` + where + `
+		GROUP BY
+			TASK_TYPE;
+	`
+
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	summary := []TypeSummary{}
+	for rows.Next() {
+		var row TypeSummary
+		err := rows.Scan(
+			&row.TaskType,
+			&row.StatusCount,
+			&row.Percentage,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		summary = append(summary, row)
+	}
+
+	return summary, rows.Err()
+}
+
+func (s *PostgresStore) GetTagSummary(filters LogFilters) ([]TagSummary, error) {
+	conditions, args := filters.conditions(1)
+	where := whereClause(conditions)
+	q := `
+		SELECT
+			TAG_NAME,
+			COUNT(LOG_TAGS.LOG_ID) AS TAG_COUNT,
+			(
+				COUNT(LOG_TAGS.LOG_ID)::FLOAT / (
+					SELECT
+						COUNT(*)
+					FROM
+						LOGS
+					` + where + `
+				)
+			) * 100 AS PERCENTAGE
+		FROM
+			LOG_TAGS
+			JOIN LOGS ON LOGS.LOG_ID = LOG_TAGS.LOG_ID
+		` + where + `
+		GROUP BY
+			TAG_NAME
+		ORDER BY
+			TAG_COUNT DESC,
+			TAG_NAME;
+	`
+
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	summary := []TagSummary{}
+	for rows.Next() {
+		var row TagSummary
+		err := rows.Scan(
+			&row.Tag,
+			&row.TagCount,
+			&row.Percentage,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		summary = append(summary, row)
+	}
+
+	return summary, rows.Err()
+}
+
+// days are bucketed in the location
+func (s *PostgresStore) GetDailyTaskCount(filters LogFilters, location *time.Location) ([]DailyTask, error) {
+	conditions, args := filters.conditions(2)
+	args = append([]any{location.String()}, args...)
+	q := `
+		SELECT
+			(CREATED_AT AT TIME ZONE $1)::DATE AS CREATED_DATE,
+			TO_CHAR((CREATED_AT AT TIME ZONE $1)::DATE, 'DD MON YYYY') AS FORMATTED_DATE,
+			COUNT(*) AS TASK_COUNT
+		FROM
+			LOGS
+		` + whereClause(conditions) + `
+		GROUP BY
+			CREATED_DATE
+		ORDER BY
+			CREATED_DATE;
+	`
+
+	// Execute the query
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Close the rows
+	defer rows.Close()
+	dailyTasks := []DailyTask{}
+
+	// Scan the rows
+	for rows.Next() {
+		var row DailyTask
+		err := rows.Scan(
+			&row.CreatedDate,
+			&row.FormattedDate,
+			&row.TaskCount,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		dailyTasks = append(dailyTasks, row)
+	}
+
+	return dailyTasks, rows.Err()
+}
+
+// logs completed in each bucket of the view, the buckets start at midnight in
+// the location of the options
+func (s *PostgresStore) GetCompletedTaskCount(options CompletedCountOptions) ([]completedCountRow, error) {
+	args := []any{options.view, options.location.String(), options.from, options.to}
+	conditions, filterArgs := options.LogFilters.conditions(len(args) + 1)
+	conditions = append([]string{
+		"logs.completed_at >= $3",
+		"logs.completed_at <= $4",
+		"logs.task_status in (" + terminalStatusesQuery + ")",
+	}, conditions...)
+	args = append(args, filterArgs...)
+
+	groupBy := "BUCKET"
+	if options.breakdown != "" {
+		groupBy += ", GROUP_KEY"
+	}
+
+	q := `
+		SELECT
+			DATE_TRUNC($1, LOGS.COMPLETED_AT AT TIME ZONE $2) AT TIME ZONE $2 AS BUCKET,
+			` + completedBreakdowns[options.breakdown] + ` AS GROUP_KEY,
+			COUNT(*) AS TASK_COUNT
+		FROM
+			LOGS
+		` + whereClause(conditions) + `
+		GROUP BY
+			` + groupBy
+
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	completedRows := []completedCountRow{}
+	for rows.Next() {
+		var row completedCountRow
+		if err := rows.Scan(&row.bucket, &row.group, &row.count); err != nil {
+			return nil, err
+		}
+
+		row.bucket = row.bucket.In(options.location)
+		completedRows = append(completedRows, row)
+	}
+
+	return completedRows, rows.Err()
+}
+
+func (s *PostgresStore) GetTaskSummary(options TaskSummaryOptions) (TaskSummary, error) {
+	q, args := taskSummaryQuery(options, func(from string, to string) string {
+		return fmt.Sprintf("extract(epoch from %s::timestamptz - %s)", to, from)
+	})
+
+	return scanTaskSummary(s.db.QueryRow(q, args...))
+}
+
+func (s *PostgresStore) AddLogLink(blockerLogId string, blockedLogId string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	// held until the end of the transaction
+	if _, err := tx.Exec("select pg_advisory_xact_lock($1)", logLinksLockKey); err != nil {
+		return err
+	}
+
+	if err := addLogLinkTx(tx, blockerLogId, blockedLogId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *PostgresStore) RemoveLogLink(blockerLogId string, blockedLogId string) error {
+	return removeLogLink(s.db, blockerLogId, blockedLogId)
+}
+
+func (s *PostgresStore) GetLogLinks(logId string) (LogLinks, error) {
+	return getLogLinks(s.db, logId)
+}
+
+// get the history events matching the filters with their changes
+func (s *PostgresStore) GetActivity(filters ActivityFilters) ([]LogEvent, error) {
+	q, args := filters.eventsQuery()
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := scanLogEvents(rows)
+	if err != nil || len(events) == 0 {
+		return events, err
+	}
+
+	q, args = filters.changesQuery(events)
+	rows, err = s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, scanLogChanges(events, rows)
+}
+
+func (s *PostgresStore) GetStatusTransitions(filters LogFilters) ([]StatusTransition, error) {
+	q, args := statusTransitionsQuery(filters)
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanStatusTransitions(rows)
+}
+
+func (s *PostgresStore) GetFlowTimes(options FlowTimeOptions) ([]FlowTime, error) {
+	q, args := flowTimesQuery(options)
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanFlowTimes(rows)
+}
+
+func (s *PostgresStore) CreateIteration(input IterationInput) (Iteration, error) {
+	projectId, err := resolveProjectId(s.db, input.ProjectId)
+	if err != nil {
+		return Iteration{}, err
+	}
+
+	input.ProjectId = projectId
+	return createIteration(s.db, input)
+}
+
+func (s *PostgresStore) GetIteration(iterationId string) (Iteration, error) {
+	return getIteration(s.db, iterationId)
+}
+
+func (s *PostgresStore) GetIterations(projectId string) ([]Iteration, error) {
+	return getIterations(s.db, projectId)
+}
+
+func (s *PostgresStore) UpdateIteration(iterationId string, input IterationInput) (Iteration, error) {
+	return updateIteration(s.db, iterationId, input, "now()")
+}
+
+func (s *PostgresStore) DeleteIteration(iterationId string) error {
+	return deleteIteration(s.db, iterationId)
+}
+
+func (s *PostgresStore) AddIterationLog(iterationId string, logId string, estimate *float64) error {
+	return addIterationLog(s.db, iterationId, logId, estimate)
+}
+
+func (s *PostgresStore) RemoveIterationLog(iterationId string, logId string) error {
+	return removeIterationLog(s.db, iterationId, logId)
+}
+
+func (s *PostgresStore) GetIterationLogs(iterationId string) ([]IterationLog, error) {
+	return getIterationLogs(s.db, iterationId)
+}
+
+func (s *PostgresStore) GetIterationTransitions(iterationId string) ([]StatusTransition, error) {
+	return getIterationTransitions(s.db, iterationId)
+}
+
+func (s *PostgresStore) GetOpenLogs(filters LogFilters) ([]OpenLog, error) {
+	q, args := openLogsQuery(filters)
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanOpenLogs(rows)
+}
+
+func (s *PostgresStore) GetVocabulary() (Vocabulary, error) {
+	return getVocabulary(s.db)
+}
+
+func (s *PostgresStore) SaveTerm(kind string, term VocabularyTerm) (VocabularyTerm, error) {
+	return saveTerm(s.db, kind, term)
+}
+
+func (s *PostgresStore) DeleteTerm(kind string, name string) error {
+	return deleteTerm(s.db, kind, name)
+}
+
+func (s *PostgresStore) SavePriority(priority Priority) (Priority, error) {
+	return savePriority(s.db, priority)
+}
+
+func (s *PostgresStore) DeletePriority(value int) error {
+	return deletePriority(s.db, value)
+}
+
+func (s *PostgresStore) StartTimer(logId string, userName string) (TimeEntry, error) {
+	return startTimer(s.db, logId, userName)
+}
+
+func (s *PostgresStore) StopTimer(logId string, userName string) (TimeEntry, error) {
+	return stopTimer(s.db, logId, userName, "now()")
+}
+
+func (s *PostgresStore) GetTimeEntries(logId string) ([]TimeEntry, error) {
+	return getTimeEntries(s.db, logId)
+}
+
+func (s *PostgresStore) CreateTimeEntry(logId string, input CreateTimeEntryInput) (TimeEntry, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	defer tx.Rollback()
+	if err := checkTimeEntryLog(tx, logId); err != nil {
+		return TimeEntry{}, err
+	}
+
+	q := "insert into time_entries (log_id, user_name, started_at, ended_at, notes) values ($1, $2, $3, $4, $5) returning " + timeEntryColumns
+	entry, err := scanTimeEntry(tx.QueryRow(q, logId, input.UserName, input.StartedAt, input.EndedAt, input.Notes))
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	if err := refreshTrackedSeconds(tx, logId); err != nil {
+		return TimeEntry{}, err
+	}
+
+	return entry, tx.Commit()
+}
+
+func (s *PostgresStore) UpdateTimeEntry(entryId string, input UpdateTimeEntryInput) (TimeEntry, error) {
+	if !uuidPattern.MatchString(entryId) {
+		return TimeEntry{}, ErrTimeEntryNotFound
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	defer tx.Rollback()
+	entry, err := getTimeEntryTx(tx, entryId)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	startedAt, endedAt, notes, err := input.apply(entry)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	q := "update time_entries set started_at = $1, ended_at = $2, notes = $3 where entry_id = $4"
+	if _, err := tx.Exec(q, startedAt, endedAt, notes, entryId); err != nil {
+		return TimeEntry{}, err
+	}
+
+	if err := refreshTrackedSeconds(tx, entry.LogId); err != nil {
+		return TimeEntry{}, err
+	}
+
+	entry, err = getTimeEntryTx(tx, entryId)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	return entry, tx.Commit()
+}
+
+func (s *PostgresStore) DeleteTimeEntry(entryId string) error {
+	return deleteTimeEntry(s.db, entryId)
+}
+
+func (s *PostgresStore) GetTimesheet(options TimesheetOptions) ([]TimesheetRow, error) {
+	args := []any{options.userName, options.from, options.end()}
+	conditions, filterArgs := options.LogFilters.conditions(len(args) + 1)
+	conditions = append([]string{
+		"time_entries.ended_at is not null",
+		"time_entries.user_name = $1",
+		"time_entries.started_at >= $2",
+		"time_entries.started_at < $3",
+	}, conditions...)
+	args = append(args, filterArgs...)
+
+	// seconds of each entry, truncated like the go stores
+	seconds := "floor(extract(epoch from time_entries.ended_at - time_entries.started_at))"
+	from := "from time_entries join logs on logs.log_id = time_entries.log_id"
+	var q string
+	switch options.groupBy {
+	case "day":
+		args = append(args, options.location.String())
+		q = fmt.Sprintf(`
+			WITH
+				DAYS AS (
+					SELECT
+						GENERATE_SERIES(
+							$2::TIMESTAMPTZ AT TIME ZONE $%[4]d,
+							($3::TIMESTAMPTZ AT TIME ZONE $%[4]d) - INTERVAL '1 day',
+							INTERVAL '1 day'
+						)::DATE AS DAY
+				),
+				ENTRIES AS (
+					SELECT
+						(TIME_ENTRIES.STARTED_AT AT TIME ZONE $%[4]d)::DATE AS DAY,
+						%[1]s AS SECONDS
+					%[2]s
+					%[3]s
+				)
+			SELECT
+				TO_CHAR(D.DAY, 'YYYY-MM-DD'),
+				TO_CHAR(D.DAY, 'YYYY-MM-DD'),
+				COALESCE(SUM(E.SECONDS), 0)::BIGINT,
+				COUNT(E.SECONDS)
+			FROM
+				DAYS D
+				LEFT JOIN ENTRIES E ON D.DAY = E.DAY
+			GROUP BY
+				D.DAY
+			ORDER BY
+				D.DAY;
+		`, seconds, from, whereClause(conditions), len(args))
+	case "log":
+		q = fmt.Sprintf("select logs.log_id::text, logs.task_name, sum(%s)::bigint, count(*) %s %s group by logs.log_id, logs.task_name", seconds, from, whereClause(conditions))
+	case "type":
+		q = fmt.Sprintf("select logs.task_type, logs.task_type, sum(%s)::bigint, count(*) %s %s group by logs.task_type", seconds, from, whereClause(conditions))
+	case "tag":
+		from += " left join log_tags on log_tags.log_id = logs.log_id"
+		q = fmt.Sprintf("select coalesce(log_tags.tag_name, ''), coalesce(log_tags.tag_name, ''), sum(%s)::bigint, count(*) %s %s group by log_tags.tag_name", seconds, from, whereClause(conditions))
+	}
+
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	timesheet := []TimesheetRow{}
+	for rows.Next() {
+		var row TimesheetRow
+		if err := rows.Scan(&row.Key, &row.Label, &row.TrackedSeconds, &row.EntryCount); err != nil {
+			return nil, err
+		}
+
+		if options.groupBy == "tag" && row.Key == "" {
+			row.Label = untaggedLabel
+		}
+
+		timesheet = append(timesheet, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sortTimesheet(timesheet, options.groupBy)
+	return timesheet, nil
+}
+
+var _ Store = (*PostgresStore)(nil)
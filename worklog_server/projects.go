@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// errors returned by the project stores
+var (
+	ErrProjectNotFound       = errors.New("no project found with this project id")
+	ErrDuplicateProjectName  = errors.New("project name already exists")
+	ErrProjectNotEmpty       = errors.New("project still has logs")
+	ErrDefaultProjectDeleted = errors.New("the default project can't be deleted")
+)
+
+type Project struct {
+	ProjectId   string    `json:"projectId"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	IsDefault   bool      `json:"isDefault"`
+	LogCount    int       `json:"logCount"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// fields to create or update a project, a nil description is left untouched
+type ProjectInput struct {
+	Name        string
+	Description *string
+}
+
+// storage of the projects owning the work logs
+type ProjectStore interface {
+	CreateProject(input ProjectInput) (Project, error)
+	GetProject(projectId string) (Project, error)
+	GetProjects() ([]Project, error)
+	UpdateProject(projectId string, input ProjectInput) (Project, error)
+	DeleteProject(projectId string) error
+}
+
+// columns selected for a Project, in the order scanProject expects them
+const projectColumns = `project_id,
+	name,
+	description,
+	is_default,
+	(select count(*) from logs where logs.project_id = projects.project_id) as log_count,
+	created_at,
+	updated_at`
+
+func scanProject(row rowScanner) (Project, error) {
+	var project Project
+	err := row.Scan(
+		&project.ProjectId,
+		&project.Name,
+		&project.Description,
+		&project.IsDefault,
+		&project.LogCount,
+		&project.CreatedAt,
+		&project.UpdatedAt,
+	)
+
+	return project, err
+}
+
+// check that the projectId filter of a request points to a project, writes
+// the error response when it doesn't
+func validateProjectScope(store ProjectStore, w http.ResponseWriter, filters LogFilters) bool {
+	if filters.projectId == "" {
+		return true
+	}
+
+	_, err := store.GetProject(filters.projectId)
+	if err == nil {
+		return true
+	}
+
+	if err == ErrProjectNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "No project found with this project id"})
+		return false
+	}
+
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+	return false
+}
+
+func handleGetProjects(store ProjectStore, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	projects, err := store.GetProjects()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while fetching projects"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Projects []Project `json:"projects"`
+	}{Projects: projects})
+}
+
+func handleGetProject(store ProjectStore, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	project, err := store.GetProject(r.PathValue("projectId"))
+	if err != nil {
+		if err == ErrProjectNotFound {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "No records found"})
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Message string  `json:"message"`
+		Project Project `json:"project"`
+	}{Message: "Ok", Project: project})
+}
+
+func handleCreateProject(store ProjectStore, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var body struct {
+		Name        string  `json:"name"`
+		Description *string `json:"description"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if strings.TrimSpace(body.Name) == "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Project name is required"})
+		return
+	}
+
+	project, err := store.CreateProject(ProjectInput{Name: strings.TrimSpace(body.Name), Description: body.Description})
+	if err != nil {
+		if err == ErrDuplicateProjectName {
+			http.Error(w, "Project name already exists", http.StatusBadRequest)
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		Message string  `json:"message"`
+		Project Project `json:"project"`
+	}{Message: "Project created successfully", Project: project})
+}
+
+func handleUpdateProject(store ProjectStore, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var body struct {
+		ProjectId   string  `json:"projectId"`
+		Name        string  `json:"name"`
+		Description *string `json:"description"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if body.ProjectId == "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Project id is required"})
+		return
+	}
+
+	if strings.TrimSpace(body.Name) == "" && body.Description == nil {
+		http.Error(w, "No valid fields to update", http.StatusBadRequest)
+		return
+	}
+
+	project, err := store.UpdateProject(body.ProjectId, ProjectInput{Name: strings.TrimSpace(body.Name), Description: body.Description})
+	if err != nil {
+		switch err {
+		case ErrProjectNotFound:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{
+				"message":   "No records found with this project id",
+				"projectId": body.ProjectId,
+			})
+		case ErrDuplicateProjectName:
+			http.Error(w, "Project name already exists", http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	json.NewEncoder(w).Encode(struct {
+		Message string  `json:"message"`
+		Project Project `json:"project"`
+	}{Message: "Project updated successfully", Project: project})
+}
+
+func handleDeleteProject(store ProjectStore, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	err := store.DeleteProject(r.PathValue("projectId"))
+	if err != nil {
+		switch err {
+		case ErrProjectNotFound:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "No project found"})
+		case ErrProjectNotEmpty, ErrDefaultProjectDeleted:
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		}
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Successfully deleted the project"})
+}
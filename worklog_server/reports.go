@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// longest date range of a timesheet, in days
+const maxTimesheetDays = 366
+
+// label of the row of the entries on logs without tags
+const untaggedLabel = "No tag"
+
+// options of a timesheet. From and To are the first and last day in the
+// location, entries are bucketed by the day they started on
+type TimesheetOptions struct {
+	LogFilters
+	from     time.Time
+	to       time.Time
+	location *time.Location
+	groupBy  string
+	userName string
+}
+
+// start of the day after the last day of the timesheet
+func (options TimesheetOptions) end() time.Time {
+	return options.to.AddDate(0, 0, 1)
+}
+
+// tracked time of a group of entries. The key is the day, log id, task type
+// or tag of the group
+type TimesheetRow struct {
+	Key            string `json:"key"`
+	Label          string `json:"label"`
+	TrackedSeconds int64  `json:"trackedSeconds"`
+	EntryCount     int    `json:"entryCount"`
+}
+
+// storage of the reports built out of the time entries
+type ReportStore interface {
+	GetTimesheet(options TimesheetOptions) ([]TimesheetRow, error)
+}
+
+// a finished time entry with the fields of its log used to group it, there
+// is one per tag of the log when grouping by tag
+type timesheetEntry struct {
+	startedAt time.Time
+	endedAt   time.Time
+	logId     string
+	taskName  string
+	taskType  string
+	tagName   string
+}
+
+// group the entries of a timesheet in go, every day of the range is listed
+// when grouping by day. Used by the stores that can't bucket in sql
+func groupTimesheet(entries []timesheetEntry, options TimesheetOptions) []TimesheetRow {
+	rows := map[string]*TimesheetRow{}
+	keys := []string{}
+	add := func(key string, label string) *TimesheetRow {
+		if row, ok := rows[key]; ok {
+			return row
+		}
+
+		rows[key] = &TimesheetRow{Key: key, Label: label}
+		keys = append(keys, key)
+		return rows[key]
+	}
+
+	if options.groupBy == "day" {
+		for day := options.from; day.Before(options.end()); day = day.AddDate(0, 0, 1) {
+			add(day.Format(time.DateOnly), day.Format(time.DateOnly))
+		}
+	}
+
+	for _, entry := range entries {
+		var row *TimesheetRow
+		switch options.groupBy {
+		case "day":
+			day := entry.startedAt.In(options.location).Format(time.DateOnly)
+			row = add(day, day)
+		case "log":
+			row = add(entry.logId, entry.taskName)
+		case "type":
+			row = add(entry.taskType, entry.taskType)
+		case "tag":
+			label := entry.tagName
+			if label == "" {
+				label = untaggedLabel
+			}
+
+			row = add(entry.tagName, label)
+		}
+
+		row.TrackedSeconds += int64(entry.endedAt.Sub(entry.startedAt).Seconds())
+		row.EntryCount++
+	}
+
+	timesheet := []TimesheetRow{}
+	for _, key := range keys {
+		timesheet = append(timesheet, *rows[key])
+	}
+
+	sortTimesheet(timesheet, options.groupBy)
+	return timesheet
+}
+
+// days in order, other groups by tracked time
+func sortTimesheet(rows []TimesheetRow, groupBy string) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if groupBy == "day" || rows[i].TrackedSeconds == rows[j].TrackedSeconds {
+			return rows[i].Key < rows[j].Key
+		}
+
+		return rows[i].TrackedSeconds > rows[j].TrackedSeconds
+	})
+}
+
+// read a YYYY-MM-DD day in the location
+func parseDay(query url.Values, key string, location *time.Location) (*time.Time, error) {
+	value := strings.TrimSpace(query.Get(key))
+	if value == "" {
+		return nil, nil
+	}
+
+	day, err := time.ParseInLocation(time.DateOnly, value, location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value %q, expected YYYY-MM-DD", key, value)
+	}
+
+	return &day, nil
+}
+
+// read the timesheet options of a request, the current week by default
+func parseTimesheetOptions(r *http.Request, vocabulary Vocabulary) (TimesheetOptions, error) {
+	query := r.URL.Query()
+	var options TimesheetOptions
+	var err error
+
+	options.LogFilters, err = parseLogFilters(query, vocabulary)
+	if err != nil {
+		return options, err
+	}
+
+	options.location, err = parseTimezone(query)
+	if err != nil {
+		return options, err
+	}
+
+	options.groupBy = query.Get("groupBy")
+	switch options.groupBy {
+	case "":
+		options.groupBy = "day"
+	case "day", "log", "type", "tag":
+	default:
+		return options, fmt.Errorf("invalid groupBy value %q", options.groupBy)
+	}
+
+	options.userName = strings.TrimSpace(query.Get("user"))
+	if options.userName == "" {
+		options.userName = requestUser(r)
+	}
+
+	from, err := parseDay(query, "from", options.location)
+	if err != nil {
+		return options, err
+	}
+
+	to, err := parseDay(query, "to", options.location)
+	if err != nil {
+		return options, err
+	}
+
+	options.from = truncateDate(time.Now().In(options.location), "week")
+	if from != nil {
+		options.from = *from
+	}
+
+	options.to = options.from.AddDate(0, 0, 6)
+	if to != nil {
+		options.to = *to
+	}
+
+	if options.to.Before(options.from) {
+		return options, fmt.Errorf("to can't be before from")
+	}
+
+	if options.to.After(options.from.AddDate(0, 0, maxTimesheetDays-1)) {
+		return options, fmt.Errorf("the timesheet can't be longer than %d days", maxTimesheetDays)
+	}
+
+	return options, nil
+}
+
+func writeTimesheetCSV(w http.ResponseWriter, rows []TimesheetRow, groupBy string) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.WriteHeader(http.StatusOK)
+	writer := csv.NewWriter(w)
+	writer.Write([]string{groupBy, "label", "tracked_seconds", "tracked_hours", "entry_count"})
+	for _, row := range rows {
+		writer.Write([]string{
+			row.Key,
+			row.Label,
+			strconv.FormatInt(row.TrackedSeconds, 10),
+			strconv.FormatFloat(float64(row.TrackedSeconds)/3600, 'f', 2, 64),
+			strconv.Itoa(row.EntryCount),
+		})
+	}
+
+	writer.Flush()
+}
+
+func handleGetTimesheet(store Store, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vocabulary, ok := loadVocabulary(store, w)
+	if !ok {
+		return
+	}
+
+	options, err := parseTimesheetOptions(r, vocabulary)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	if !validateProjectScope(store, w, options.LogFilters) {
+		return
+	}
+
+	rows, err := store.GetTimesheet(options)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while building the timesheet"})
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		writeTimesheetCSV(w, rows, options.groupBy)
+		return
+	}
+
+	// entries on logs with several tags are in several rows, there is no
+	// total for them
+	var total *int64
+	if options.groupBy != "tag" {
+		total = new(int64)
+		for _, row := range rows {
+			*total += row.TrackedSeconds
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		From           string         `json:"from"`
+		To             string         `json:"to"`
+		Timezone       string         `json:"timezone"`
+		GroupBy        string         `json:"groupBy"`
+		User           string         `json:"user"`
+		TrackedSeconds *int64         `json:"trackedSeconds,omitempty"`
+		Rows           []TimesheetRow `json:"rows"`
+	}{
+		From:           options.from.Format(time.DateOnly),
+		To:             options.to.Format(time.DateOnly),
+		Timezone:       options.location.String(),
+		GroupBy:        options.groupBy,
+		User:           options.userName,
+		TrackedSeconds: total,
+		Rows:           rows,
+	})
+}
@@ -0,0 +1,746 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// layout of every time stored in sqlite. It has a fixed width so comparing
+// the text follows the time order
+const sqliteTimeFormat = "2006-01-02 15:04:05.000+00:00"
+
+// current time in sqliteTimeFormat
+const sqliteNow = `strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')`
+
+// convert the time arguments of a query to sqliteTimeFormat
+func sqliteArgs(args []any) []any {
+	converted := make([]any, len(args))
+	for i, arg := range args {
+		switch value := arg.(type) {
+		case time.Time:
+			converted[i] = value.UTC().Format(sqliteTimeFormat)
+		case *time.Time:
+			if value == nil {
+				converted[i] = nil
+			} else {
+				converted[i] = value.UTC().Format(sqliteTimeFormat)
+			}
+		default:
+			converted[i] = arg
+		}
+	}
+
+	return converted
+}
+
+// sqlite implementation of the LogStore, for single user installs
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{db: db}
+}
+
+// open the sqlite database file without touching the schema. Transactions
+// begin immediate, they take the write lock up front and wait for each other
+// instead of failing when a read turns into a write
+func OpenSQLite(path string) *sql.DB {
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite&_txlock=immediate", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := db.Ping(); err != nil {
+		panic(err)
+	}
+
+	log.Println("SQLite database opened at " + path)
+	return db
+}
+
+// open the sqlite database file and apply the pending migrations
+func ConnectToSQLite(path string) *sql.DB {
+	db := OpenSQLite(path)
+	if err := MigrateUp(db, "sqlite"); err != nil {
+		panic(err)
+	}
+
+	return db
+}
+
+// log writes shared with the postgres store
+var sqliteDialect = logDialect{now: sqliteNow, args: sqliteArgs}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) exec(q string, args ...any) (sql.Result, error) {
+	return s.db.Exec(q, sqliteArgs(args)...)
+}
+
+func (s *SQLiteStore) query(q string, args ...any) (*sql.Rows, error) {
+	return s.db.Query(q, sqliteArgs(args)...)
+}
+
+func (s *SQLiteStore) queryRow(q string, args ...any) *sql.Row {
+	return s.db.QueryRow(q, sqliteArgs(args)...)
+}
+
+// the transactions begin immediate so the parent writes are serialised
+func (s *SQLiteStore) CreateLog(input CreateLogInput) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	if err := createLogTx(tx, sqliteDialect, input); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) GetLogById(logId string) (WorkLog, error) {
+	q := "select " + workLogColumns + " from logs where log_id = $1"
+	workLog, err := scanWorkLog(s.queryRow(q, logId))
+	if err == sql.ErrNoRows {
+		return workLog, ErrLogNotFound
+	}
+
+	return workLog, err
+}
+
+// the transactions begin immediate so the parent writes are serialised
+func (s *SQLiteStore) UpdateLog(logId string, input UpdateLogInput) (WorkLog, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return WorkLog{}, err
+	}
+
+	defer tx.Rollback()
+	if err := updateLogTx(tx, sqliteDialect, logId, input); err != nil {
+		return WorkLog{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return WorkLog{}, err
+	}
+
+	return s.GetLogById(logId)
+}
+
+// get a log with all of its subtasks
+func (s *SQLiteStore) GetLogTree(logId string) (LogTree, error) {
+	root, err := s.GetLogById(logId)
+	if err != nil {
+		return LogTree{}, err
+	}
+
+	q := logDescendantsQuery + " select " + workLogColumns + " from logs where log_id in (select log_id from descendants) order by created_at, log_id"
+	rows, err := s.query(q, logId)
+	if err != nil {
+		return LogTree{}, err
+	}
+
+	defer rows.Close()
+	descendants := []WorkLog{}
+	for rows.Next() {
+		workLog, err := scanWorkLog(rows)
+		if err != nil {
+			return LogTree{}, err
+		}
+
+		descendants = append(descendants, workLog)
+	}
+
+	if err := rows.Err(); err != nil {
+		return LogTree{}, err
+	}
+
+	return buildLogTree(root, descendants), nil
+}
+
+// delete a log, its subtasks are deleted too with cascade or moved up to
+// the parent of the log otherwise
+func (s *SQLiteStore) DeleteLog(logId string, cascade bool) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	if _, err := deleteLogTx(tx, logId, cascade, sqliteNow); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// delete logs, their subtasks are deleted too with cascade or moved up to
+// the closest ancestor that stays otherwise
+func (s *SQLiteStore) DeleteLogs(logIds []string, cascade bool) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+	rowCount, err := deleteLogsTx(tx, logIds, cascade, sqliteNow)
+	if err != nil {
+		return 0, err
+	}
+
+	return rowCount, tx.Commit()
+}
+
+// build an fts5 match expression out of the user's search text,
+// every word is quoted and matched as a prefix
+func getFTSMatchExpression(userQuery string) string {
+	terms := []string{}
+	for _, word := range strings.Fields(userQuery) {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
+	}
+
+	return strings.Join(terms, " OR ")
+}
+
+// get a page of the logs matching the filters
+func (s *SQLiteStore) GetAllLogs(options GetAllLogsOpts) (LogsPage, error) {
+	sortBy, sortOrder, err := options.orderBy()
+	if err != nil {
+		return LogsPage{}, err
+	}
+
+	limit := options.pageLimit()
+	offset := 0
+	if options.page > 0 {
+		offset = options.page * limit
+	}
+
+	conditions, args := options.conditions(1)
+	from := "logs"
+	totalCount := "count(*) over()"
+	// sortBy and sortOrder come from the allowlist in orderBy
+	orderBy := fmt.Sprintf("logs.%s %s, logs.log_id", sortBy, sortOrder)
+
+	// ranked search on the fts index, bm25 is lower for better matches
+	if strings.TrimSpace(options.s) != "" {
+		args = append(args, getFTSMatchExpression(options.s))
+		from = fmt.Sprintf(`logs join (
+				select log_id, bm25(logs_fts) as rank
+				from logs_fts
+				where logs_fts match $%d
+			) as matches using (log_id)`, len(args))
+		orderBy = "matches.rank, logs.log_id"
+	}
+
+	// keyset pagination, search results are ordered by the sort column
+	// instead of the rank so the cursor stays stable
+	if options.cursorMode {
+		keyset, keysetArgs, err := options.keysetCondition(len(args) + 1)
+		if err != nil {
+			return LogsPage{}, err
+		}
+
+		conditions = append(conditions, keyset...)
+		args = append(args, keysetArgs...)
+		totalCount = "0"
+		orderBy = fmt.Sprintf("logs.%s %s, logs.log_id %s", sortBy, sortOrder, sortOrder)
+		offset = 0
+		// one more row to know if there is a next page
+		limit++
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "where " + strings.Join(conditions, " and ")
+	}
+
+	args = append(args, limit, offset)
+	q := fmt.Sprintf(`select
+			%s,
+			%s as total_count
+		from %s
+		%s
+		order by %s
+		limit $%d offset $%d`, workLogColumns, totalCount, from, where, orderBy, len(args)-1, len(args))
+
+	rows, err := s.query(q, args...)
+	if err != nil {
+		return LogsPage{}, err
+	}
+
+	defer rows.Close()
+	logs := []WorkLog{}
+	count := 0
+	for rows.Next() {
+		logEntry, err := scanWorkLog(rows, &count)
+		if err != nil {
+			return LogsPage{}, err
+		}
+
+		logs = append(logs, logEntry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return LogsPage{}, err
+	}
+
+	if options.cursorMode {
+		return cursorPage(logs, options, limit-1)
+	}
+
+	totalPages := int(math.Ceil(float64(count) / float64(limit)))
+	return LogsPage{Logs: logs, TotalPages: totalPages}, nil
+}
+
+func (s *SQLiteStore) GetStatusSummary(filters LogFilters) ([]StatusSummary, error) {
+	conditions, args := filters.conditions(1)
+	where := whereClause(conditions)
+	q := `
+		select
+			task_status,
+			count(task_status) as status_count,
+			count(task_status) * 100.0 / (select count(*) from logs ` + where + `) as percentage
+		from logs
+		` + where + `
+		group by task_status`
+
+	rows, err := s.query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	summary := []StatusSummary{}
+	for rows.Next() {
+		var row StatusSummary
+		if err := rows.Scan(&row.TaskStatus, &row.StatusCount, &row.Percentage); err != nil {
+			return nil, err
+		}
+
+		summary = append(summary, row)
+	}
+
+	return summary, rows.Err()
+}
+
+func (s *SQLiteStore) GetTypeSummary(filters LogFilters) ([]TypeSummary, error) {
+	conditions, args := filters.conditions(1)
+	where := whereClause(conditions)
+	q := `
+		select
+			task_type,
+			count(task_type) as type_count,
+			count(task_type) * 100.0 / (select count(*) from logs ` + where + `) as percentage
+		from logs
+		` + where + `
+		group by task_type`
+
+	rows, err := s.query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	summary := []TypeSummary{}
+	for rows.Next() {
+		var row TypeSummary
+		if err := rows.Scan(&row.TaskType, &row.StatusCount, &row.Percentage); err != nil {
+			return nil, err
+		}
+
+		summary = append(summary, row)
+	}
+
+	return summary, rows.Err()
+}
+
+func (s *SQLiteStore) GetTagSummary(filters LogFilters) ([]TagSummary, error) {
+	conditions, args := filters.conditions(1)
+	where := whereClause(conditions)
+	q := `
+		select
+			tag_name,
+			count(log_tags.log_id) as tag_count,
+			count(log_tags.log_id) * 100.0 / (select count(*) from logs ` + where + `) as percentage
+		from log_tags
+		join logs on logs.log_id = log_tags.log_id
+		` + where + `
+		group by tag_name
+		order by tag_count desc, tag_name`
+
+	rows, err := s.query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	summary := []TagSummary{}
+	for rows.Next() {
+		var row TagSummary
+		if err := rows.Scan(&row.Tag, &row.TagCount, &row.Percentage); err != nil {
+			return nil, err
+		}
+
+		summary = append(summary, row)
+	}
+
+	return summary, rows.Err()
+}
+
+// sqlite only knows utc, so the days of the location are bucketed here
+func (s *SQLiteStore) GetDailyTaskCount(filters LogFilters, location *time.Location) ([]DailyTask, error) {
+	conditions, args := filters.conditions(1)
+	rows, err := s.query("select created_at from logs "+whereClause(conditions), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	counts := map[string]int{}
+	for rows.Next() {
+		var createdAt time.Time
+		if err := rows.Scan(&createdAt); err != nil {
+			return nil, err
+		}
+
+		counts[createdAt.In(location).Format(time.DateOnly)]++
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	dailyTasks := []DailyTask{}
+	for createdDate, count := range counts {
+		row := DailyTask{TaskCount: count}
+		row.CreatedDate, err = time.Parse(time.DateOnly, createdDate)
+		if err != nil {
+			return nil, err
+		}
+
+		// same as TO_CHAR(..., 'DD MON YYYY') in postgres
+		row.FormattedDate = strings.ToUpper(row.CreatedDate.Format("02 Jan 2006"))
+		dailyTasks = append(dailyTasks, row)
+	}
+
+	sort.Slice(dailyTasks, func(i, j int) bool {
+		return dailyTasks[i].CreatedDate.Before(dailyTasks[j].CreatedDate)
+	})
+
+	return dailyTasks, nil
+}
+
+// sqlite has no date_trunc or timezones, so the buckets are built here
+func (s *SQLiteStore) GetCompletedTaskCount(options CompletedCountOptions) ([]completedCountRow, error) {
+	conditions, args := options.LogFilters.conditions(3)
+	conditions = append([]string{
+		"logs.completed_at >= $1",
+		"logs.completed_at <= $2",
+		"logs.task_status in (" + terminalStatusesQuery + ")",
+	}, conditions...)
+	args = append([]any{options.from, options.to}, args...)
+
+	q := "select logs.completed_at, " + completedBreakdowns[options.breakdown] + " from logs " + whereClause(conditions)
+	rows, err := s.query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	completedRows := []completedCountRow{}
+	for rows.Next() {
+		row := completedCountRow{count: 1}
+		var completedAt time.Time
+		if err := rows.Scan(&completedAt, &row.group); err != nil {
+			return nil, err
+		}
+
+		row.bucket = truncateDate(completedAt.In(options.location), options.view)
+		completedRows = append(completedRows, row)
+	}
+
+	return completedRows, rows.Err()
+}
+
+func (s *SQLiteStore) GetTaskSummary(options TaskSummaryOptions) (TaskSummary, error) {
+	q, args := taskSummaryQuery(options, func(from string, to string) string {
+		return fmt.Sprintf("(julianday(%s) - julianday(%s)) * 86400", to, from)
+	})
+
+	return scanTaskSummary(s.queryRow(q, args...))
+}
+
+// the transactions begin immediate so the link writes are serialised
+func (s *SQLiteStore) AddLogLink(blockerLogId string, blockedLogId string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	if err := addLogLinkTx(tx, blockerLogId, blockedLogId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) RemoveLogLink(blockerLogId string, blockedLogId string) error {
+	return removeLogLink(s.db, blockerLogId, blockedLogId)
+}
+
+func (s *SQLiteStore) GetLogLinks(logId string) (LogLinks, error) {
+	return getLogLinks(s.db, logId)
+}
+
+// get the history events matching the filters with their changes
+func (s *SQLiteStore) GetActivity(filters ActivityFilters) ([]LogEvent, error) {
+	q, args := filters.eventsQuery()
+	rows, err := s.query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := scanLogEvents(rows)
+	if err != nil || len(events) == 0 {
+		return events, err
+	}
+
+	q, args = filters.changesQuery(events)
+	rows, err = s.query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, scanLogChanges(events, rows)
+}
+
+func (s *SQLiteStore) GetStatusTransitions(filters LogFilters) ([]StatusTransition, error) {
+	q, args := statusTransitionsQuery(filters)
+	rows, err := s.query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanStatusTransitions(rows)
+}
+
+func (s *SQLiteStore) GetFlowTimes(options FlowTimeOptions) ([]FlowTime, error) {
+	q, args := flowTimesQuery(options)
+	rows, err := s.query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanFlowTimes(rows)
+}
+
+func (s *SQLiteStore) CreateIteration(input IterationInput) (Iteration, error) {
+	projectId, err := resolveProjectId(s.db, input.ProjectId)
+	if err != nil {
+		return Iteration{}, err
+	}
+
+	input.ProjectId = projectId
+	return createIteration(s.db, input)
+}
+
+func (s *SQLiteStore) GetIteration(iterationId string) (Iteration, error) {
+	return getIteration(s.db, iterationId)
+}
+
+func (s *SQLiteStore) GetIterations(projectId string) ([]Iteration, error) {
+	return getIterations(s.db, projectId)
+}
+
+func (s *SQLiteStore) UpdateIteration(iterationId string, input IterationInput) (Iteration, error) {
+	return updateIteration(s.db, iterationId, input, sqliteNow)
+}
+
+func (s *SQLiteStore) DeleteIteration(iterationId string) error {
+	return deleteIteration(s.db, iterationId)
+}
+
+func (s *SQLiteStore) AddIterationLog(iterationId string, logId string, estimate *float64) error {
+	return addIterationLog(s.db, iterationId, logId, estimate)
+}
+
+func (s *SQLiteStore) RemoveIterationLog(iterationId string, logId string) error {
+	return removeIterationLog(s.db, iterationId, logId)
+}
+
+func (s *SQLiteStore) GetIterationLogs(iterationId string) ([]IterationLog, error) {
+	return getIterationLogs(s.db, iterationId)
+}
+
+func (s *SQLiteStore) GetIterationTransitions(iterationId string) ([]StatusTransition, error) {
+	return getIterationTransitions(s.db, iterationId)
+}
+
+func (s *SQLiteStore) GetOpenLogs(filters LogFilters) ([]OpenLog, error) {
+	q, args := openLogsQuery(filters)
+	rows, err := s.query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanOpenLogs(rows)
+}
+
+func (s *SQLiteStore) GetVocabulary() (Vocabulary, error) {
+	return getVocabulary(s.db)
+}
+
+func (s *SQLiteStore) SaveTerm(kind string, term VocabularyTerm) (VocabularyTerm, error) {
+	return saveTerm(s.db, kind, term)
+}
+
+func (s *SQLiteStore) DeleteTerm(kind string, name string) error {
+	return deleteTerm(s.db, kind, name)
+}
+
+func (s *SQLiteStore) SavePriority(priority Priority) (Priority, error) {
+	return savePriority(s.db, priority)
+}
+
+func (s *SQLiteStore) DeletePriority(value int) error {
+	return deletePriority(s.db, value)
+}
+
+func (s *SQLiteStore) StartTimer(logId string, userName string) (TimeEntry, error) {
+	return startTimer(s.db, logId, userName)
+}
+
+func (s *SQLiteStore) StopTimer(logId string, userName string) (TimeEntry, error) {
+	return stopTimer(s.db, logId, userName, sqliteNow)
+}
+
+func (s *SQLiteStore) GetTimeEntries(logId string) ([]TimeEntry, error) {
+	return getTimeEntries(s.db, logId)
+}
+
+func (s *SQLiteStore) CreateTimeEntry(logId string, input CreateTimeEntryInput) (TimeEntry, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	defer tx.Rollback()
+	if err := checkTimeEntryLog(tx, logId); err != nil {
+		return TimeEntry{}, err
+	}
+
+	q := "insert into time_entries (log_id, user_name, started_at, ended_at, notes) values ($1, $2, $3, $4, $5) returning " + timeEntryColumns
+	entry, err := scanTimeEntry(tx.QueryRow(q, sqliteArgs([]any{logId, input.UserName, input.StartedAt, input.EndedAt, input.Notes})...))
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	if err := refreshTrackedSeconds(tx, logId); err != nil {
+		return TimeEntry{}, err
+	}
+
+	return entry, tx.Commit()
+}
+
+func (s *SQLiteStore) UpdateTimeEntry(entryId string, input UpdateTimeEntryInput) (TimeEntry, error) {
+	if !uuidPattern.MatchString(entryId) {
+		return TimeEntry{}, ErrTimeEntryNotFound
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	defer tx.Rollback()
+	entry, err := getTimeEntryTx(tx, entryId)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	startedAt, endedAt, notes, err := input.apply(entry)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	q := "update time_entries set started_at = $1, ended_at = $2, notes = $3 where entry_id = $4"
+	if _, err := tx.Exec(q, sqliteArgs([]any{startedAt, endedAt, notes, entryId})...); err != nil {
+		return TimeEntry{}, err
+	}
+
+	if err := refreshTrackedSeconds(tx, entry.LogId); err != nil {
+		return TimeEntry{}, err
+	}
+
+	entry, err = getTimeEntryTx(tx, entryId)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	return entry, tx.Commit()
+}
+
+func (s *SQLiteStore) DeleteTimeEntry(entryId string) error {
+	return deleteTimeEntry(s.db, entryId)
+}
+
+func (s *SQLiteStore) GetTimesheet(options TimesheetOptions) ([]TimesheetRow, error) {
+	conditions, args := options.LogFilters.conditions(4)
+	conditions = append([]string{
+		"time_entries.ended_at is not null",
+		"time_entries.user_name = $1",
+		"time_entries.started_at >= $2",
+		"time_entries.started_at < $3",
+	}, conditions...)
+	args = append([]any{options.userName, options.from, options.end()}, args...)
+
+	tagName, join := "''", ""
+	if options.groupBy == "tag" {
+		tagName, join = "coalesce(log_tags.tag_name, '')", "left join log_tags on log_tags.log_id = logs.log_id"
+	}
+
+	q := fmt.Sprintf(`
+		select time_entries.started_at, time_entries.ended_at, logs.log_id, logs.task_name, logs.task_type, %s
+		from time_entries
+		join logs on logs.log_id = time_entries.log_id
+		%s
+		%s`, tagName, join, whereClause(conditions))
+
+	rows, err := s.query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	entries := []timesheetEntry{}
+	for rows.Next() {
+		var entry timesheetEntry
+		if err := rows.Scan(&entry.startedAt, &entry.endedAt, &entry.logId, &entry.taskName, &entry.taskType, &entry.tagName); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return groupTimesheet(entries, options), nil
+}
+
+var _ Store = (*SQLiteStore)(nil)
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// sqlite store on a new database file with every migration applied
+func newTestStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	store := NewSQLiteStore(ConnectToSQLite(filepath.Join(t.TempDir(), "worklog.db")))
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// create a log in the default project, the task name must be unique
+func createTestLog(t *testing.T, store *SQLiteStore, input CreateLogInput) WorkLog {
+	t.Helper()
+	if input.TaskType == "" {
+		input.TaskType = "task"
+	}
+
+	if input.TaskStatus == "" {
+		input.TaskStatus = "backlog"
+	}
+
+	if input.Priority == 0 {
+		input.Priority = 1
+	}
+
+	if err := store.CreateLog(input); err != nil {
+		t.Fatalf("CreateLog(%q) error = %v", input.TaskName, err)
+	}
+
+	var logId string
+	if err := store.queryRow("select log_id from logs where task_name = $1", input.TaskName).Scan(&logId); err != nil {
+		t.Fatal(err)
+	}
+
+	workLog, err := store.GetLogById(logId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return workLog
+}
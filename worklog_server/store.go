@@ -0,0 +1,320 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// errors returned by the log stores
+var (
+	ErrLogNotFound       = errors.New("no records found with this log id")
+	ErrDuplicateTaskName = errors.New("task name already exists")
+)
+
+// LogStore is the storage used by the http handlers, every backend
+// (postgres, sqlite, ...) implements it
+type LogStore interface {
+	CreateLog(input CreateLogInput) error
+	GetLogById(logId string) (WorkLog, error)
+	GetLogTree(logId string) (LogTree, error)
+	UpdateLog(logId string, input UpdateLogInput) (WorkLog, error)
+	DeleteLog(logId string, cascade bool) error
+	DeleteLogs(logIds []string, cascade bool) (int64, error)
+	GetAllLogs(options GetAllLogsOpts) (LogsPage, error)
+
+	GetStatusSummary(filters LogFilters) ([]StatusSummary, error)
+	GetTypeSummary(filters LogFilters) ([]TypeSummary, error)
+	GetTagSummary(filters LogFilters) ([]TagSummary, error)
+	GetDailyTaskCount(filters LogFilters, location *time.Location) ([]DailyTask, error)
+	GetCompletedTaskCount(options CompletedCountOptions) ([]completedCountRow, error)
+	GetTaskSummary(options TaskSummaryOptions) (TaskSummary, error)
+
+	Close() error
+}
+
+// storage of the logs and the projects owning them
+type Store interface {
+	LogStore
+	ProjectStore
+	LinkStore
+	HistoryStore
+	TransitionStore
+	VocabularyStore
+	TimeEntryStore
+	ReportStore
+	AnalyticsStore
+	IterationStore
+	InsightStore
+}
+
+// fields required to create a new log
+type CreateLogInput struct {
+	ProjectId   string
+	ParentLogId string
+	TaskName    string
+	TaskType    string
+	TaskStatus  string
+	Notes       string
+	StartedAt   *time.Time
+	CompletedAt *time.Time
+	DueAt       *time.Time
+	Priority    int
+	Tags        []string
+}
+
+// fields to update on a log, zero values are left untouched. An empty
+// ParentLogId turns a subtask back into a top level log, the clear flags
+// remove the started, completed and due times
+type UpdateLogInput struct {
+	ProjectId   string
+	ParentLogId *string
+	TaskName    string
+	TaskType    string
+	TaskStatus  string
+	Notes       string
+	StartedAt   *time.Time
+	CompletedAt *time.Time
+	DueAt       *time.Time
+	Priority    *int
+	Tags        *[]string
+
+	ClearStartedAt   bool
+	ClearCompletedAt bool
+	ClearDueAt       bool
+}
+
+// check if there is nothing to update
+func (input UpdateLogInput) isEmpty() bool {
+	return input.ProjectId == "" &&
+		input.ParentLogId == nil &&
+		input.TaskName == "" &&
+		input.TaskType == "" &&
+		input.TaskStatus == "" &&
+		input.Notes == "" &&
+		input.StartedAt == nil &&
+		input.CompletedAt == nil &&
+		input.DueAt == nil &&
+		input.Priority == nil &&
+		input.Tags == nil &&
+		!input.ClearStartedAt &&
+		!input.ClearCompletedAt &&
+		!input.ClearDueAt
+}
+
+// options to list and search the logs. In cursor mode the logs after the
+// cursor are returned instead of a page, a nil cursor is the first page
+type GetAllLogsOpts struct {
+	LogFilters
+	s          string
+	sortBy     string
+	sortOrder  string
+	limit      int
+	page       int
+	cursorMode bool
+	cursor     *logCursor
+}
+
+// bounds of the number of logs returned at once
+const (
+	defaultLogsLimit = 10
+	maxLogsLimit     = 100
+)
+
+// number of logs to return, bounded by maxLogsLimit
+func (options GetAllLogsOpts) pageLimit() int {
+	if options.limit <= 0 {
+		return defaultLogsLimit
+	}
+
+	return min(options.limit, maxLogsLimit)
+}
+
+// a page of logs, TotalPages is only set in page mode and NextCursor only in
+// cursor mode when there are more logs
+type LogsPage struct {
+	Logs       []WorkLog
+	TotalPages int
+	NextCursor string
+}
+
+// columns the logs can be sorted by, keyed by the accepted sortBy values
+var sortableColumns = map[string]string{
+	"task_name":    "task_name",
+	"taskName":     "task_name",
+	"task_type":    "task_type",
+	"taskType":     "task_type",
+	"task_status":  "task_status",
+	"taskStatus":   "task_status",
+	"priority":     "priority",
+	"notes":        "notes",
+	"started_at":   "started_at",
+	"startedAt":    "started_at",
+	"completed_at": "completed_at",
+	"completedAt":  "completed_at",
+	"created_at":   "created_at",
+	"createdAt":    "created_at",
+	"updated_at":   "updated_at",
+	"updatedAt":    "updated_at",
+}
+
+// sort column and direction of the options, only allowlisted values are
+// returned so they are safe to put in the order by clause
+func (options GetAllLogsOpts) orderBy() (string, string, error) {
+	column := "updated_at"
+	if options.sortBy != "" {
+		var ok bool
+		column, ok = sortableColumns[options.sortBy]
+		if !ok {
+			return "", "", fmt.Errorf("invalid sortBy value %q", options.sortBy)
+		}
+	}
+
+	direction := "desc"
+	if options.sortOrder != "" {
+		direction = strings.ToLower(options.sortOrder)
+		if direction != "asc" && direction != "desc" {
+			return "", "", fmt.Errorf("invalid sortOrder value %q", options.sortOrder)
+		}
+	}
+
+	return column, direction, nil
+}
+
+type StatusSummary struct {
+	TaskStatus  string  `json:"taskStatus"`
+	StatusCount int     `json:"statusCount"`
+	Percentage  float64 `json:"percentage"`
+}
+
+type TypeSummary struct {
+	TaskType    string  `json:"taskType"`
+	StatusCount int     `json:"statusCount"`
+	Percentage  float64 `json:"percentage"`
+}
+
+type TagSummary struct {
+	Tag        string  `json:"tag"`
+	TagCount   int     `json:"tagCount"`
+	Percentage float64 `json:"percentage"`
+}
+
+type DailyTask struct {
+	CreatedDate   time.Time `json:"createdDate"`
+	FormattedDate string    `json:"formattedDate"`
+	TaskCount     int       `json:"taskCount"`
+}
+
+type CompletedCount struct {
+	CompletedAt time.Time `json:"completedAt"`
+	TaskCount   int       `json:"taskCount"`
+}
+
+// counts of the logs matching the filters. Cycle time is from started to
+// completed on terminal logs, the age of a bug is since it was created
+type TaskSummary struct {
+	TotalTasks              int     `json:"totalTasks"`
+	TotalBugs               int     `json:"totalBugs"`
+	TotalProgressTasks      int     `json:"totalProgressTasks"`
+	HighestPriorityTasks    int     `json:"highestPriorityTasks"`
+	OverdueTasks            int     `json:"overdueTasks"`
+	CompletedThisWeek       int     `json:"completedThisWeek"`
+	AvgCycleTimeSeconds     float64 `json:"avgCycleTimeSeconds"`
+	OpenBugs                int     `json:"openBugs"`
+	AvgOpenBugAgeSeconds    float64 `json:"avgOpenBugAgeSeconds"`
+	OldestOpenBugAgeSeconds float64 `json:"oldestOpenBugAgeSeconds"`
+}
+
+// columns selected for a WorkLog, in the order scanWorkLog expects them
+const workLogColumns = `log_id,
+	task_name,
+	task_type,
+	task_status,
+	notes,
+	started_at,
+	completed_at,
+	created_at,
+	updated_at,
+	priority,
+	tag_names,
+	project_id,
+	parent_log_id,
+	tracked_seconds,
+	due_at`
+
+// ids of the logs, projects and other rows, checked before they reach a
+// query since postgres rejects malformed uuids
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// anything that can scan a row, *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scan the workLogColumns of a row, extra destinations are scanned after them
+func scanWorkLog(row rowScanner, extra ...any) (WorkLog, error) {
+	var (
+		workLog     WorkLog
+		notes       sql.NullString
+		startedAt   sql.NullTime
+		completedAt sql.NullTime
+		dueAt       sql.NullTime
+		tagNames    string
+		parentLogId sql.NullString
+	)
+
+	dest := []any{
+		&workLog.LogId,
+		&workLog.TaskName,
+		&workLog.TaskType,
+		&workLog.TaskStatus,
+		&notes,
+		&startedAt,
+		&completedAt,
+		&workLog.CreatedAt,
+		&workLog.UpdatedAt,
+		&workLog.Priority,
+		&tagNames,
+		&workLog.ProjectId,
+		&parentLogId,
+		&workLog.TrackedSeconds,
+		&dueAt,
+	}
+
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return workLog, err
+	}
+
+	if notes.Valid {
+		workLog.Notes = notes.String
+	} else {
+		workLog.Notes = "n/a"
+	}
+
+	if startedAt.Valid {
+		workLog.StartedAt = &startedAt.Time
+	} else {
+		workLog.StartedAt = nil
+	}
+
+	if completedAt.Valid {
+		workLog.CompletedAt = &completedAt.Time
+	} else {
+		workLog.CompletedAt = nil
+	}
+
+	if dueAt.Valid {
+		workLog.DueAt = &dueAt.Time
+	}
+
+	if parentLogId.Valid {
+		workLog.ParentLogId = &parentLogId.String
+	}
+
+	workLog.Tags = splitTagNames(tagNames)
+	return workLog, nil
+}
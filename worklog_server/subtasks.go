@@ -0,0 +1,239 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// errors returned when nesting a log under a parent
+var (
+	ErrParentNotFound = errors.New("no parent log found with this log id")
+	ErrParentCycle    = errors.New("a log can't be nested under itself or one of its subtasks")
+	ErrParentProject  = errors.New("a subtask must be in the project of its parent")
+)
+
+// ids of $1 and of every log above it. union instead of union all stops the
+// recursion if a cycle ever gets in
+const logAncestorsQuery = `with recursive ancestors (log_id, parent_log_id) as (
+		select log_id, parent_log_id from logs where log_id = $1
+		union
+		select logs.log_id, logs.parent_log_id from logs join ancestors on logs.log_id = ancestors.parent_log_id
+	)`
+
+// ids of the subtasks of $1, subtasks of subtasks included
+const logDescendantsQuery = `with recursive descendants (log_id) as (
+		select log_id from logs where parent_log_id = $1
+		union
+		select logs.log_id from logs join descendants on logs.parent_log_id = descendants.log_id
+	)`
+
+// progress of the subtasks of a log, every level below it included
+type LogRollup struct {
+	SubtaskCount      int            `json:"subtaskCount"`
+	StatusCounts      map[string]int `json:"statusCounts"`
+	EarliestStartedAt *time.Time     `json:"earliestStartedAt"`
+	LatestCompletedAt *time.Time     `json:"latestCompletedAt"`
+	TrackedSeconds    int64          `json:"trackedSeconds"`
+}
+
+// a log with its subtasks, logs without subtasks have no rollup
+type LogTree struct {
+	WorkLog
+	Children []LogTree  `json:"children"`
+	Rollup   *LogRollup `json:"rollup,omitempty"`
+}
+
+// check that parentLogId can become the parent of logId in the project,
+// logId is empty for a log that is being created. The stores serialise the
+// parent writes so two moves checked at the same time can't close a cycle
+// together
+func checkLogParent(tx *sql.Tx, logId string, parentLogId string, projectId string) error {
+	if !uuidPattern.MatchString(parentLogId) {
+		return ErrParentNotFound
+	}
+
+	var parentProjectId string
+	err := tx.QueryRow("select project_id from logs where log_id = $1", parentLogId).Scan(&parentProjectId)
+	if err == sql.ErrNoRows {
+		return ErrParentNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if parentProjectId != projectId {
+		return ErrParentProject
+	}
+
+	if logId == "" {
+		return nil
+	}
+
+	var exists int
+
+	// the log can't end up above itself
+	err = tx.QueryRow(logAncestorsQuery+" select 1 from ancestors where log_id = $2", parentLogId, logId).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return ErrParentCycle
+}
+
+// read the children rule of a delete, subtasks move up to the parent of
+// the deleted log unless they are deleted with it
+func parseChildrenRule(value string) (cascade bool, ok bool) {
+	switch value {
+	case "", "reparent":
+		return false, true
+	case "delete":
+		return true, true
+	}
+
+	return false, false
+}
+
+// delete a log inside a transaction and record it in the history, now is
+// the sql of the current time of the database. Returns the number of logs
+// deleted
+func deleteLogTx(tx *sql.Tx, logId string, cascade bool, now string) (int64, error) {
+	deleted, err := queryLogsTx(tx, "select "+workLogColumns+" from logs where log_id = $1", logId)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(deleted) == 0 {
+		return 0, ErrLogNotFound
+	}
+
+	moved := []WorkLog{}
+	q := "delete from logs where log_id = $1"
+	if cascade {
+		descendants, err := queryLogsTx(tx, logDescendantsQuery+" select "+workLogColumns+" from logs where log_id in (select log_id from descendants)", logId)
+		if err != nil {
+			return 0, err
+		}
+
+		deleted = append(deleted, descendants...)
+		q = logDescendantsQuery + " delete from logs where log_id = $1 or log_id in (select log_id from descendants)"
+	} else {
+		moved, err = queryLogsTx(tx, "select "+workLogColumns+" from logs where parent_log_id = $1", logId)
+		if err != nil {
+			return 0, err
+		}
+
+		reparent := "update logs set parent_log_id = (select parent_log_id from logs where log_id = $1), updated_at = " + now + " where parent_log_id = $1"
+		if _, err := tx.Exec(reparent, logId); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := tx.Exec(q, logId); err != nil {
+		return 0, err
+	}
+
+	if err := recordLogDeletes(tx, deleted, moved); err != nil {
+		return 0, err
+	}
+
+	return int64(len(deleted)), nil
+}
+
+// delete logs one after the other so their subtasks follow the same rule as
+// a single delete. A subtask of a deleted log moves up to the closest
+// ancestor that stays, and logs already deleted with an ancestor are skipped
+func deleteLogsTx(tx *sql.Tx, logIds []string, cascade bool, now string) (int64, error) {
+	var rowCount int64
+	for _, logId := range logIds {
+		deleted, err := deleteLogTx(tx, logId, cascade, now)
+		if err == ErrLogNotFound {
+			continue
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		rowCount += deleted
+	}
+
+	return rowCount, nil
+}
+
+// nest the descendants of root under it and roll up their progress
+func buildLogTree(root WorkLog, descendants []WorkLog) LogTree {
+	children := map[string][]WorkLog{}
+	for _, workLog := range descendants {
+		if workLog.ParentLogId != nil {
+			children[*workLog.ParentLogId] = append(children[*workLog.ParentLogId], workLog)
+		}
+	}
+
+	// every log is built once, a cycle in the data can't recurse forever
+	built := map[string]bool{}
+	var build func(workLog WorkLog) LogTree
+	build = func(workLog WorkLog) LogTree {
+		built[workLog.LogId] = true
+		tree := LogTree{WorkLog: workLog, Children: []LogTree{}}
+		for _, child := range children[workLog.LogId] {
+			if !built[child.LogId] {
+				tree.Children = append(tree.Children, build(child))
+			}
+		}
+
+		if len(tree.Children) > 0 {
+			tree.Rollup = rollupLogTree(tree.Children)
+		}
+
+		return tree
+	}
+
+	return build(root)
+}
+
+func rollupLogTree(children []LogTree) *LogRollup {
+	rollup := &LogRollup{StatusCounts: map[string]int{}}
+	for _, child := range children {
+		rollup.SubtaskCount++
+		rollup.StatusCounts[child.TaskStatus]++
+		rollup.EarliestStartedAt = earliestTime(rollup.EarliestStartedAt, child.StartedAt)
+		rollup.LatestCompletedAt = latestTime(rollup.LatestCompletedAt, child.CompletedAt)
+		rollup.TrackedSeconds += child.TrackedSeconds
+
+		// the rollup of a child already covers its own subtasks
+		if child.Rollup != nil {
+			rollup.SubtaskCount += child.Rollup.SubtaskCount
+			for status, count := range child.Rollup.StatusCounts {
+				rollup.StatusCounts[status] += count
+			}
+
+			rollup.EarliestStartedAt = earliestTime(rollup.EarliestStartedAt, child.Rollup.EarliestStartedAt)
+			rollup.LatestCompletedAt = latestTime(rollup.LatestCompletedAt, child.Rollup.LatestCompletedAt)
+			rollup.TrackedSeconds += child.Rollup.TrackedSeconds
+		}
+	}
+
+	return rollup
+}
+
+func earliestTime(a *time.Time, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.Before(*a)) {
+		return b
+	}
+
+	return a
+}
+
+func latestTime(a *time.Time, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.After(*a)) {
+		return b
+	}
+
+	return a
+}
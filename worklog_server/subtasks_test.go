@@ -0,0 +1,168 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCheckLogParent(t *testing.T) {
+	store := newTestStore(t)
+	other, err := store.CreateProject(ProjectInput{Name: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := createTestLog(t, store, CreateLogInput{TaskName: "a"})
+	b := createTestLog(t, store, CreateLogInput{TaskName: "b", ParentLogId: a.LogId})
+	c := createTestLog(t, store, CreateLogInput{TaskName: "c", ParentLogId: b.LogId})
+	d := createTestLog(t, store, CreateLogInput{TaskName: "d"})
+	elsewhere := createTestLog(t, store, CreateLogInput{TaskName: "elsewhere", ProjectId: other.ProjectId})
+
+	parent := func(logId string) *string { return &logId }
+	tests := []struct {
+		name  string
+		logId string
+		input UpdateLogInput
+		want  error
+	}{
+		{"itself", a.LogId, UpdateLogInput{ParentLogId: parent(a.LogId)}, ErrParentCycle},
+		{"its subtask", a.LogId, UpdateLogInput{ParentLogId: parent(b.LogId)}, ErrParentCycle},
+		{"a subtask of its subtask", a.LogId, UpdateLogInput{ParentLogId: parent(c.LogId)}, ErrParentCycle},
+		{"unknown parent", d.LogId, UpdateLogInput{ParentLogId: parent("5f0c2a8e-2a47-4c3b-9b0e-1d2f3a4b5c6d")}, ErrParentNotFound},
+		{"parent in another project", d.LogId, UpdateLogInput{ParentLogId: parent(elsewhere.LogId)}, ErrParentProject},
+		{"subtask moved away from its parent", c.LogId, UpdateLogInput{ProjectId: other.ProjectId}, ErrParentProject},
+		{"log with subtasks moved away from them", a.LogId, UpdateLogInput{ProjectId: other.ProjectId}, ErrParentProject},
+		{"log and parent in the new project", d.LogId, UpdateLogInput{ProjectId: other.ProjectId, ParentLogId: parent(elsewhere.LogId)}, nil},
+		{"sibling", c.LogId, UpdateLogInput{ParentLogId: parent(a.LogId)}, nil},
+		{"top level", b.LogId, UpdateLogInput{ParentLogId: parent("")}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := store.UpdateLog(test.logId, test.input); err != test.want {
+				t.Errorf("UpdateLog() error = %v, want %v", err, test.want)
+			}
+		})
+	}
+
+	if err := store.CreateLog(CreateLogInput{TaskName: "e", TaskType: "task", TaskStatus: "backlog", Priority: 1, ParentLogId: elsewhere.LogId}); err != ErrParentProject {
+		t.Errorf("CreateLog() under a parent of another project error = %v, want %v", err, ErrParentProject)
+	}
+}
+
+func TestConcurrentReparents(t *testing.T) {
+	store := newTestStore(t)
+	for round := 0; round < 20; round++ {
+		a := createTestLog(t, store, CreateLogInput{TaskName: "a" + string(rune('a'+round))})
+		b := createTestLog(t, store, CreateLogInput{TaskName: "b" + string(rune('a'+round))})
+
+		// a under b and b under a at the same time, one of them must fail
+		errs := make([]error, 2)
+		var wg sync.WaitGroup
+		for i, move := range [][2]string{{a.LogId, b.LogId}, {b.LogId, a.LogId}} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				parentLogId := move[1]
+				_, errs[i] = store.UpdateLog(move[0], UpdateLogInput{ParentLogId: &parentLogId})
+			}()
+		}
+
+		wg.Wait()
+		if (errs[0] == nil) == (errs[1] == nil) {
+			t.Fatalf("round %d: errors = %v, want exactly one move to fail", round, errs)
+		}
+
+		for _, err := range errs {
+			if err != nil && err != ErrParentCycle {
+				t.Fatalf("round %d: error = %v, want %v", round, err, ErrParentCycle)
+			}
+		}
+	}
+}
+
+func TestBuildLogTreeCycle(t *testing.T) {
+	// a cycle can't be written anymore, a tree over old data still ends
+	parent := func(logId string) *string { return &logId }
+	root := WorkLog{LogId: "a", ParentLogId: parent("b")}
+	descendants := []WorkLog{{LogId: "b", ParentLogId: parent("a")}, root}
+
+	tree := buildLogTree(root, descendants)
+	if len(tree.Children) != 1 || tree.Children[0].LogId != "b" || len(tree.Children[0].Children) != 0 {
+		t.Errorf("buildLogTree() = %+v, want a with the single subtask b", tree)
+	}
+}
+
+func TestDeleteLogChildren(t *testing.T) {
+	tests := []struct {
+		name    string
+		cascade bool
+		bulk    bool
+		// logs left and their parent after deleting b out of a > b > c > d
+		want map[string]string
+	}{
+		{"reparent", false, false, map[string]string{"a": "", "c": "a", "d": "c"}},
+		{"delete", true, false, map[string]string{"a": ""}},
+		{"bulk reparent", false, true, map[string]string{"a": "", "d": "a"}},
+		{"bulk delete", true, true, map[string]string{"a": ""}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newTestStore(t)
+			logs := map[string]WorkLog{}
+			parentLogId := ""
+			for _, name := range []string{"a", "b", "c", "d"} {
+				logs[name] = createTestLog(t, store, CreateLogInput{TaskName: name, ParentLogId: parentLogId})
+				parentLogId = logs[name].LogId
+			}
+
+			// bulk deletes take c too, d then moves up to the closest log
+			// that stays
+			if test.bulk {
+				deleted, err := store.DeleteLogs([]string{logs["b"].LogId, logs["c"].LogId}, test.cascade)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if want := int64(4 - len(test.want)); deleted != want {
+					t.Errorf("DeleteLogs() = %d, want %d", deleted, want)
+				}
+			} else if err := store.DeleteLog(logs["b"].LogId, test.cascade); err != nil {
+				t.Fatal(err)
+			}
+
+			names := map[string]string{"": ""}
+			for name, workLog := range logs {
+				names[workLog.LogId] = name
+			}
+
+			got := map[string]string{}
+			for name, workLog := range logs {
+				left, err := store.GetLogById(workLog.LogId)
+				if err == ErrLogNotFound {
+					continue
+				}
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got[name] = ""
+				if left.ParentLogId != nil {
+					got[name] = names[*left.ParentLogId]
+				}
+			}
+
+			if len(got) != len(test.want) {
+				t.Fatalf("logs left = %v, want %v", got, test.want)
+			}
+
+			for name, parent := range test.want {
+				if got[name] != parent {
+					t.Errorf("parent of %s = %q, want %q", name, got[name], parent)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// options of a task summary. Logs due before now are overdue, the ones
+// completed since weekStart count for the current week
+type TaskSummaryOptions struct {
+	LogFilters
+	highestPriority int
+	weekStart       time.Time
+	now             time.Time
+}
+
+// query of a task summary, every count is an aggregate over the filtered
+// logs so the table is read once. seconds returns the sql of the seconds
+// between two times, which isn't the same for postgres and sqlite
+func taskSummaryQuery(options TaskSummaryOptions, seconds func(from string, to string) string) (string, []any) {
+	conditions, args := options.LogFilters.conditions(4)
+	args = append([]any{options.highestPriority, options.weekStart, options.now}, args...)
+	q := `
+		with filtered as (
+			select logs.*, logs.task_status in (` + terminalStatusesQuery + `) as is_done
+			from logs
+			` + whereClause(conditions) + `
+		)
+		select
+			count(*),
+			count(*) filter (where task_type = 'bug'),
+			count(*) filter (where task_status in (` + startedStatusesQuery + `)),
+			count(*) filter (where priority = $1),
+			count(*) filter (where due_at < $3 and not is_done),
+			count(*) filter (where is_done and completed_at >= $2),
+			coalesce(avg(` + seconds("started_at", "completed_at") + `) filter (where is_done and started_at is not null and completed_at is not null), 0),
+			count(*) filter (where task_type = 'bug' and not is_done),
+			coalesce(avg(` + seconds("created_at", "$3") + `) filter (where task_type = 'bug' and not is_done), 0),
+			coalesce(max(` + seconds("created_at", "$3") + `) filter (where task_type = 'bug' and not is_done), 0)
+		from filtered`
+
+	return q, args
+}
+
+func scanTaskSummary(row *sql.Row) (TaskSummary, error) {
+	var summary TaskSummary
+	err := row.Scan(
+		&summary.TotalTasks,
+		&summary.TotalBugs,
+		&summary.TotalProgressTasks,
+		&summary.HighestPriorityTasks,
+		&summary.OverdueTasks,
+		&summary.CompletedThisWeek,
+		&summary.AvgCycleTimeSeconds,
+		&summary.OpenBugs,
+		&summary.AvgOpenBugAgeSeconds,
+		&summary.OldestOpenBugAgeSeconds,
+	)
+
+	return summary, err
+}
+
+func handleGetTaskSummary(store Store, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vocabulary, ok := loadVocabulary(store, w)
+	if !ok {
+		return
+	}
+
+	filters, err := parseLogFilters(r.URL.Query(), vocabulary)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	if !validateProjectScope(store, w, filters) {
+		return
+	}
+
+	// the week starts on monday in the timezone of the request
+	location, err := parseTimezone(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	now := time.Now().UTC()
+	summary, err := store.GetTaskSummary(TaskSummaryOptions{
+		LogFilters:      filters,
+		highestPriority: vocabulary.highestPriority(),
+		weekStart:       truncateDate(now.In(location), "week").UTC(),
+		now:             now,
+	})
+
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while generate summary"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(summary)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// tag names are lower case words like "auth", "sprint-12" or "team/api"
+var tagNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_./:-]{0,49}$`)
+
+// lower case, validate and de-duplicate the tag names, sorted by name
+func normalizeTags(tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !tagNamePattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q", tag)
+		}
+
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// replace the tags of a log, the tags must be normalized
+func setLogTags(tx *sql.Tx, logId string, tags []string) error {
+	if _, err := tx.Exec("delete from log_tags where log_id = $1", logId); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		if _, err := tx.Exec("insert into tags (name) values ($1) on conflict do nothing", tag); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("insert into log_tags (log_id, tag_name) values ($1, $2)", logId, tag); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec("update logs set tag_names = $1 where log_id = $2", strings.Join(tags, " "), logId)
+	return err
+}
+
+// tag names stored in the logs.tag_names column
+func splitTagNames(tagNames string) []string {
+	tags := strings.Fields(tagNames)
+	if tags == nil {
+		return []string{}
+	}
+
+	return tags
+}
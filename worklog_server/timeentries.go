@@ -0,0 +1,461 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// errors returned by the time entry stores
+var (
+	ErrTimeEntryNotFound = errors.New("no time entry found with this id")
+	ErrTimerRunning      = errors.New("a timer is already running for this user")
+	ErrNoRunningTimer    = errors.New("no timer is running on this log for this user")
+	ErrInvalidTimeRange  = errors.New("ended at can't be before started at")
+)
+
+// header naming the user of a request, DEFAULT_USER is used without it
+const userHeader = "X-Worklog-User"
+
+// a sitting spent on a log, EndedAt is nil while the timer runs. The
+// duration of a running entry is the time since it started
+type TimeEntry struct {
+	EntryId         string     `json:"entryId"`
+	LogId           string     `json:"logId"`
+	UserName        string     `json:"userName"`
+	StartedAt       time.Time  `json:"startedAt"`
+	EndedAt         *time.Time `json:"endedAt"`
+	DurationSeconds int64      `json:"durationSeconds"`
+	Notes           string     `json:"notes"`
+	CreatedAt       time.Time  `json:"createdAt"`
+}
+
+// fields of a finished time entry created by hand
+type CreateTimeEntryInput struct {
+	UserName  string
+	StartedAt time.Time
+	EndedAt   time.Time
+	Notes     string
+}
+
+// fields to update on a time entry, nil values are left untouched
+type UpdateTimeEntryInput struct {
+	StartedAt *time.Time
+	EndedAt   *time.Time
+	Notes     *string
+}
+
+// storage of the time entries of the logs. Every change updates the
+// tracked seconds of the log
+type TimeEntryStore interface {
+	StartTimer(logId string, userName string) (TimeEntry, error)
+	StopTimer(logId string, userName string) (TimeEntry, error)
+	GetTimeEntries(logId string) ([]TimeEntry, error)
+	CreateTimeEntry(logId string, input CreateTimeEntryInput) (TimeEntry, error)
+	UpdateTimeEntry(entryId string, input UpdateTimeEntryInput) (TimeEntry, error)
+	DeleteTimeEntry(entryId string) error
+}
+
+// columns selected for a TimeEntry, in the order scanTimeEntry expects them
+const timeEntryColumns = "entry_id, log_id, user_name, started_at, ended_at, notes, created_at"
+
+func scanTimeEntry(row rowScanner) (TimeEntry, error) {
+	var entry TimeEntry
+	var endedAt sql.NullTime
+	err := row.Scan(&entry.EntryId, &entry.LogId, &entry.UserName, &entry.StartedAt, &endedAt, &entry.Notes, &entry.CreatedAt)
+	if err != nil {
+		return entry, err
+	}
+
+	end := time.Now()
+	if endedAt.Valid {
+		entry.EndedAt = &endedAt.Time
+		end = endedAt.Time
+	}
+
+	entry.DurationSeconds = int64(end.Sub(entry.StartedAt).Seconds())
+	return entry, nil
+}
+
+func getTimeEntryTx(tx *sql.Tx, entryId string) (TimeEntry, error) {
+	entry, err := scanTimeEntry(tx.QueryRow("select "+timeEntryColumns+" from time_entries where entry_id = $1", entryId))
+	if err == sql.ErrNoRows {
+		return entry, ErrTimeEntryNotFound
+	}
+
+	return entry, err
+}
+
+// check that a log exists before adding entries to it
+func checkTimeEntryLog(tx *sql.Tx, logId string) error {
+	if !uuidPattern.MatchString(logId) {
+		return ErrLogNotFound
+	}
+
+	var exists int
+	err := tx.QueryRow("select 1 from logs where log_id = $1", logId).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return ErrLogNotFound
+	}
+
+	return err
+}
+
+// check that the user has no running timer, the partial unique index
+// rejects the insert anyway
+func checkNoRunningTimer(tx *sql.Tx, userName string) error {
+	var exists int
+	err := tx.QueryRow("select 1 from time_entries where user_name = $1 and ended_at is null", userName).Scan(&exists)
+	if err == nil {
+		return ErrTimerRunning
+	}
+
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	return nil
+}
+
+// times of an entry once updated, the end stays nil while the timer runs
+func (input UpdateTimeEntryInput) apply(entry TimeEntry) (time.Time, *time.Time, string, error) {
+	startedAt, endedAt, notes := entry.StartedAt, entry.EndedAt, entry.Notes
+	if input.StartedAt != nil {
+		startedAt = *input.StartedAt
+	}
+
+	if input.EndedAt != nil {
+		endedAt = input.EndedAt
+	}
+
+	if input.Notes != nil {
+		notes = *input.Notes
+	}
+
+	if endedAt != nil && endedAt.Before(startedAt) {
+		return startedAt, endedAt, notes, ErrInvalidTimeRange
+	}
+
+	return startedAt, endedAt, notes, nil
+}
+
+// sum the finished entries of a log into logs.tracked_seconds
+func refreshTrackedSeconds(tx *sql.Tx, logId string) error {
+	rows, err := tx.Query("select started_at, ended_at from time_entries where log_id = $1 and ended_at is not null", logId)
+	if err != nil {
+		return err
+	}
+
+	var seconds float64
+	for rows.Next() {
+		var startedAt, endedAt time.Time
+		if err := rows.Scan(&startedAt, &endedAt); err != nil {
+			rows.Close()
+			return err
+		}
+
+		seconds += endedAt.Sub(startedAt).Seconds()
+	}
+
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("update logs set tracked_seconds = $1 where log_id = $2", int64(seconds), logId)
+	return err
+}
+
+// start a timer on a log now
+func startTimer(db *sql.DB, logId string, userName string) (TimeEntry, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	defer tx.Rollback()
+	if err := checkTimeEntryLog(tx, logId); err != nil {
+		return TimeEntry{}, err
+	}
+
+	if err := checkNoRunningTimer(tx, userName); err != nil {
+		return TimeEntry{}, err
+	}
+
+	q := "insert into time_entries (log_id, user_name) values ($1, $2) returning " + timeEntryColumns
+	entry, err := scanTimeEntry(tx.QueryRow(q, logId, userName))
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	return entry, tx.Commit()
+}
+
+// stop the running timer of a user on a log, now is the sql of the current
+// time of the database
+func stopTimer(db *sql.DB, logId string, userName string, now string) (TimeEntry, error) {
+	if !uuidPattern.MatchString(logId) {
+		return TimeEntry{}, ErrNoRunningTimer
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	defer tx.Rollback()
+	var entryId string
+	q := "update time_entries set ended_at = " + now + " where log_id = $1 and user_name = $2 and ended_at is null returning entry_id"
+	err = tx.QueryRow(q, logId, userName).Scan(&entryId)
+	if err == sql.ErrNoRows {
+		return TimeEntry{}, ErrNoRunningTimer
+	}
+
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	if err := refreshTrackedSeconds(tx, logId); err != nil {
+		return TimeEntry{}, err
+	}
+
+	entry, err := getTimeEntryTx(tx, entryId)
+	if err != nil {
+		return TimeEntry{}, err
+	}
+
+	return entry, tx.Commit()
+}
+
+// entries of a log, oldest first
+func getTimeEntries(db *sql.DB, logId string) ([]TimeEntry, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+	if err := checkTimeEntryLog(tx, logId); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query("select "+timeEntryColumns+" from time_entries where log_id = $1 order by started_at, entry_id", logId)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	entries := []TimeEntry{}
+	for rows.Next() {
+		entry, err := scanTimeEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+func deleteTimeEntry(db *sql.DB, entryId string) error {
+	if !uuidPattern.MatchString(entryId) {
+		return ErrTimeEntryNotFound
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+	var logId string
+	err = tx.QueryRow("delete from time_entries where entry_id = $1 returning log_id", entryId).Scan(&logId)
+	if err == sql.ErrNoRows {
+		return ErrTimeEntryNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := refreshTrackedSeconds(tx, logId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// user of a request, from the X-Worklog-User header or DEFAULT_USER
+func requestUser(r *http.Request) string {
+	if userName := strings.TrimSpace(r.Header.Get(userHeader)); userName != "" {
+		return userName
+	}
+
+	return GetSecrets().defaultUser
+}
+
+func writeTimeEntryError(w http.ResponseWriter, err error) {
+	switch err {
+	case ErrLogNotFound:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "No log found"})
+	case ErrTimeEntryNotFound, ErrNoRunningTimer:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+	case ErrTimerRunning:
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+	case ErrInvalidTimeRange:
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+	}
+}
+
+func writeTimeEntry(w http.ResponseWriter, status int, message string, entry TimeEntry) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Message   string    `json:"message"`
+		TimeEntry TimeEntry `json:"timeEntry"`
+	}{Message: message, TimeEntry: entry})
+}
+
+func handleStartTimer(store TimeEntryStore, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	entry, err := store.StartTimer(r.PathValue("logId"), requestUser(r))
+	if err != nil {
+		writeTimeEntryError(w, err)
+		return
+	}
+
+	writeTimeEntry(w, http.StatusCreated, "Timer started", entry)
+}
+
+func handleStopTimer(store TimeEntryStore, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	entry, err := store.StopTimer(r.PathValue("logId"), requestUser(r))
+	if err != nil {
+		writeTimeEntryError(w, err)
+		return
+	}
+
+	writeTimeEntry(w, http.StatusOK, "Timer stopped", entry)
+}
+
+func handleGetTimeEntries(store TimeEntryStore, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	entries, err := store.GetTimeEntries(r.PathValue("logId"))
+	if err != nil {
+		writeTimeEntryError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		TimeEntries []TimeEntry `json:"timeEntries"`
+	}{TimeEntries: entries})
+}
+
+// add a finished entry by hand, for work done away from the timer
+func handleCreateTimeEntry(store TimeEntryStore, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var body struct {
+		StartedAt *time.Time `json:"startedAt"`
+		EndedAt   *time.Time `json:"endedAt"`
+		Notes     string     `json:"notes"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if body.StartedAt == nil || body.EndedAt == nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Started at and ended at are required"})
+		return
+	}
+
+	input := CreateTimeEntryInput{
+		UserName:  requestUser(r),
+		StartedAt: body.StartedAt.UTC(),
+		EndedAt:   body.EndedAt.UTC(),
+		Notes:     strings.TrimSpace(body.Notes),
+	}
+
+	if input.EndedAt.Before(input.StartedAt) {
+		writeTimeEntryError(w, ErrInvalidTimeRange)
+		return
+	}
+
+	entry, err := store.CreateTimeEntry(r.PathValue("logId"), input)
+	if err != nil {
+		writeTimeEntryError(w, err)
+		return
+	}
+
+	writeTimeEntry(w, http.StatusCreated, "Time entry created", entry)
+}
+
+func handleUpdateTimeEntry(store TimeEntryStore, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var body struct {
+		StartedAt *time.Time `json:"startedAt"`
+		EndedAt   *time.Time `json:"endedAt"`
+		Notes     *string    `json:"notes"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	input := UpdateTimeEntryInput{Notes: body.Notes}
+	if body.StartedAt != nil {
+		startedAt := body.StartedAt.UTC()
+		input.StartedAt = &startedAt
+	}
+
+	if body.EndedAt != nil {
+		endedAt := body.EndedAt.UTC()
+		input.EndedAt = &endedAt
+	}
+
+	if input.Notes != nil {
+		notes := strings.TrimSpace(*input.Notes)
+		input.Notes = &notes
+	}
+
+	if input.StartedAt == nil && input.EndedAt == nil && input.Notes == nil {
+		http.Error(w, "No valid fields to update", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := store.UpdateTimeEntry(r.PathValue("entryId"), input)
+	if err != nil {
+		writeTimeEntryError(w, err)
+		return
+	}
+
+	writeTimeEntry(w, http.StatusOK, "Time entry updated", entry)
+}
+
+func handleDeleteTimeEntry(store TimeEntryStore, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	err := store.DeleteTimeEntry(r.PathValue("entryId"))
+	if err != nil {
+		writeTimeEntryError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Successfully deleted"})
+}
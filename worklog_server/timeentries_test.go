@@ -0,0 +1,75 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRunningTimer(t *testing.T) {
+	store := newTestStore(t)
+	a := createTestLog(t, store, CreateLogInput{TaskName: "a"})
+	b := createTestLog(t, store, CreateLogInput{TaskName: "b"})
+
+	steps := []struct {
+		name     string
+		start    bool
+		logId    string
+		userName string
+		want     error
+	}{
+		{"start", true, a.LogId, "ada", nil},
+		{"start on another log", true, b.LogId, "ada", ErrTimerRunning},
+		{"start on the same log", true, a.LogId, "ada", ErrTimerRunning},
+		{"start for another user", true, a.LogId, "bob", nil},
+		{"stop on a log without a timer", false, b.LogId, "ada", ErrNoRunningTimer},
+		{"stop", false, a.LogId, "ada", nil},
+		{"stop again", false, a.LogId, "ada", ErrNoRunningTimer},
+		{"start once stopped", true, b.LogId, "ada", nil},
+	}
+
+	for _, step := range steps {
+		var err error
+		if step.start {
+			_, err = store.StartTimer(step.logId, step.userName)
+		} else {
+			_, err = store.StopTimer(step.logId, step.userName)
+		}
+
+		if err != step.want {
+			t.Fatalf("%s: error = %v, want %v", step.name, err, step.want)
+		}
+	}
+}
+
+func TestConcurrentTimers(t *testing.T) {
+	store := newTestStore(t)
+	logIds := []string{}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		logIds = append(logIds, createTestLog(t, store, CreateLogInput{TaskName: name}).LogId)
+	}
+
+	// the same user starts a timer on every log at once, only one runs
+	errs := make([]error, len(logIds))
+	var wg sync.WaitGroup
+	for i, logId := range logIds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = store.StartTimer(logId, "ada")
+		}()
+	}
+
+	wg.Wait()
+	started := 0
+	for _, err := range errs {
+		if err == nil {
+			started++
+		} else if err != ErrTimerRunning {
+			t.Errorf("StartTimer() error = %v, want %v", err, ErrTimerRunning)
+		}
+	}
+
+	if started != 1 {
+		t.Errorf("%d timers started, want 1", started)
+	}
+}
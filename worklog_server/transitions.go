@@ -0,0 +1,71 @@
+package main
+
+import (
+	"database/sql"
+	"time"
+)
+
+// a log entering a status, FromStatus is nil when the log was created
+type StatusTransition struct {
+	LogId          string    `json:"logId"`
+	FromStatus     *string   `json:"fromStatus"`
+	ToStatus       string    `json:"toStatus"`
+	TransitionedAt time.Time `json:"transitionedAt"`
+}
+
+// storage of the status transitions of the logs
+type TransitionStore interface {
+	GetStatusTransitions(filters LogFilters) ([]StatusTransition, error)
+}
+
+// record the status change between two versions of a log inside the
+// transaction that made it, before is the zero WorkLog for a create
+func recordStatusTransition(tx *sql.Tx, before WorkLog, after WorkLog) error {
+	if before.TaskStatus == after.TaskStatus {
+		return nil
+	}
+
+	fromStatus := sql.NullString{String: before.TaskStatus, Valid: before.TaskStatus != ""}
+	q := "insert into log_status_transitions (log_id, from_status, to_status) values ($1, $2, $3)"
+	_, err := tx.Exec(q, after.LogId, fromStatus, after.TaskStatus)
+	return err
+}
+
+// transitions of the logs matching the filters, ordered by log and time.
+// The transitions are deleted with their log, so the analytics built on them
+// leave out the deleted logs on past days too
+func statusTransitionsQuery(filters LogFilters) (string, []any) {
+	conditions, args := filters.conditions(1)
+	q := `
+		select
+			log_status_transitions.log_id,
+			log_status_transitions.from_status,
+			log_status_transitions.to_status,
+			log_status_transitions.transitioned_at
+		from log_status_transitions
+		join logs on logs.log_id = log_status_transitions.log_id
+		` + whereClause(conditions) + `
+		order by log_status_transitions.log_id, log_status_transitions.transitioned_at, log_status_transitions.transition_id`
+
+	return q, args
+}
+
+func scanStatusTransitions(rows *sql.Rows) ([]StatusTransition, error) {
+	defer rows.Close()
+	transitions := []StatusTransition{}
+	for rows.Next() {
+		var transition StatusTransition
+		var fromStatus sql.NullString
+		if err := rows.Scan(&transition.LogId, &fromStatus, &transition.ToStatus, &transition.TransitionedAt); err != nil {
+			return nil, err
+		}
+
+		if fromStatus.Valid {
+			transition.FromStatus = &fromStatus.String
+		}
+
+		transitions = append(transitions, transition)
+	}
+
+	return transitions, rows.Err()
+}
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// errors returned by the vocabulary stores and checks
+var (
+	ErrTermNotFound    = errors.New("no value found with this name")
+	ErrTermInUse       = errors.New("the value is still used by some logs")
+	ErrTermInWorkflow  = errors.New("the status is still used by the workflow")
+	ErrTermUnreachable = errors.New("no status of the workflow can move to the new status, add it to the workflow file first")
+)
+
+// names of task types and statuses, like "bug" or "code-review"
+var termNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,49}$`)
+
+// a task type or a task status. A log in a started status is being worked
+// on and a log in a terminal status is done
+type VocabularyTerm struct {
+	Name       string `json:"name"`
+	Label      string `json:"label"`
+	Position   int    `json:"position"`
+	IsStarted  bool   `json:"isStarted,omitempty"`
+	IsTerminal bool   `json:"isTerminal,omitempty"`
+}
+
+type Priority struct {
+	Value    int    `json:"value"`
+	Label    string `json:"label"`
+	Position int    `json:"position"`
+}
+
+// allowed values of the fields of a log, ordered by position
+type Vocabulary struct {
+	TaskTypes    []VocabularyTerm `json:"taskTypes"`
+	TaskStatuses []VocabularyTerm `json:"taskStatuses"`
+	Priorities   []Priority       `json:"priorities"`
+}
+
+// storage of the vocabularies, kind is task-types or task-statuses
+type VocabularyStore interface {
+	GetVocabulary() (Vocabulary, error)
+	SaveTerm(kind string, term VocabularyTerm) (VocabularyTerm, error)
+	DeleteTerm(kind string, name string) error
+	SavePriority(priority Priority) (Priority, error)
+	DeletePriority(value int) error
+}
+
+// table of each kind of term, the logs column using it and the sql of its
+// started and terminal flags
+var termKinds = map[string]struct {
+	table     string
+	logColumn string
+	started   string
+	terminal  string
+}{
+	"task-types":    {"task_types", "task_type", "false", "false"},
+	"task-statuses": {"task_statuses", "task_status", "is_started", "is_terminal"},
+}
+
+// names of the started and the terminal statuses, as subqueries
+const (
+	startedStatusesQuery  = "select name from task_statuses where is_started"
+	terminalStatusesQuery = "select name from task_statuses where is_terminal"
+)
+
+func (vocabulary Vocabulary) validateTaskType(taskType string) bool {
+	return termPosition(vocabulary.TaskTypes, taskType) >= 0
+}
+
+func (vocabulary Vocabulary) validateTaskStatus(taskStatus string) bool {
+	return termPosition(vocabulary.TaskStatuses, taskStatus) >= 0
+}
+
+func (vocabulary Vocabulary) isStarted(taskStatus string) bool {
+	i := termPosition(vocabulary.TaskStatuses, taskStatus)
+	return i >= 0 && vocabulary.TaskStatuses[i].IsStarted
+}
+
+func (vocabulary Vocabulary) isTerminal(taskStatus string) bool {
+	i := termPosition(vocabulary.TaskStatuses, taskStatus)
+	return i >= 0 && vocabulary.TaskStatuses[i].IsTerminal
+}
+
+func (vocabulary Vocabulary) validatePriority(priority int) bool {
+	for _, known := range vocabulary.Priorities {
+		if known.Value == priority {
+			return true
+		}
+	}
+
+	return false
+}
+
+// priority given to logs created without one
+func (vocabulary Vocabulary) lowestPriority() int {
+	if len(vocabulary.Priorities) == 0 {
+		return 1
+	}
+
+	lowest := vocabulary.Priorities[0].Value
+	for _, priority := range vocabulary.Priorities {
+		lowest = min(lowest, priority.Value)
+	}
+
+	return lowest
+}
+
+func (vocabulary Vocabulary) highestPriority() int {
+	highest := 0
+	for _, priority := range vocabulary.Priorities {
+		highest = max(highest, priority.Value)
+	}
+
+	return highest
+}
+
+// label of a task type or a priority value, the key itself when unknown
+func (vocabulary Vocabulary) label(kind string, key string) string {
+	switch kind {
+	case "type":
+		if i := termPosition(vocabulary.TaskTypes, key); i >= 0 {
+			return vocabulary.TaskTypes[i].Label
+		}
+	case "priority":
+		for _, priority := range vocabulary.Priorities {
+			if strconv.Itoa(priority.Value) == key {
+				return priority.Label
+			}
+		}
+	}
+
+	return key
+}
+
+// index of a term in the list, -1 when it isn't there
+func termPosition(terms []VocabularyTerm, name string) int {
+	for i, term := range terms {
+		if term.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// sort names by the position of their term, unknown names go last
+func sortByTerms(names []string, terms []VocabularyTerm) {
+	rank := func(name string) int {
+		if i := termPosition(terms, name); i >= 0 {
+			return i
+		}
+
+		return len(terms)
+	}
+
+	sort.SliceStable(names, func(i, j int) bool {
+		if rank(names[i]) != rank(names[j]) {
+			return rank(names[i]) < rank(names[j])
+		}
+
+		return names[i] < names[j]
+	})
+}
+
+// every status of the vocabulary in the summary, in workflow order
+func completeStatusSummary(summary []StatusSummary, vocabulary Vocabulary) []StatusSummary {
+	rows := map[string]StatusSummary{}
+	names := []string{}
+	for _, row := range summary {
+		rows[row.TaskStatus] = row
+		names = append(names, row.TaskStatus)
+	}
+
+	for _, term := range vocabulary.TaskStatuses {
+		if _, ok := rows[term.Name]; !ok {
+			rows[term.Name] = StatusSummary{TaskStatus: term.Name}
+			names = append(names, term.Name)
+		}
+	}
+
+	sortByTerms(names, vocabulary.TaskStatuses)
+	completed := []StatusSummary{}
+	for _, name := range names {
+		completed = append(completed, rows[name])
+	}
+
+	return completed
+}
+
+// every type of the vocabulary in the summary, ordered by position
+func completeTypeSummary(summary []TypeSummary, vocabulary Vocabulary) []TypeSummary {
+	rows := map[string]TypeSummary{}
+	names := []string{}
+	for _, row := range summary {
+		rows[row.TaskType] = row
+		names = append(names, row.TaskType)
+	}
+
+	for _, term := range vocabulary.TaskTypes {
+		if _, ok := rows[term.Name]; !ok {
+			rows[term.Name] = TypeSummary{TaskType: term.Name}
+			names = append(names, term.Name)
+		}
+	}
+
+	sortByTerms(names, vocabulary.TaskTypes)
+	completed := []TypeSummary{}
+	for _, name := range names {
+		completed = append(completed, rows[name])
+	}
+
+	return completed
+}
+
+// read the vocabularies
+func getVocabulary(db *sql.DB) (Vocabulary, error) {
+	vocabulary := Vocabulary{TaskTypes: []VocabularyTerm{}, TaskStatuses: []VocabularyTerm{}, Priorities: []Priority{}}
+	for kind, dest := range map[string]*[]VocabularyTerm{"task-types": &vocabulary.TaskTypes, "task-statuses": &vocabulary.TaskStatuses} {
+		rows, err := db.Query("select name, label, position, " + termKinds[kind].started + ", " + termKinds[kind].terminal + " from " + termKinds[kind].table + " order by position, name")
+		if err != nil {
+			return vocabulary, err
+		}
+
+		for rows.Next() {
+			var term VocabularyTerm
+			if err := rows.Scan(&term.Name, &term.Label, &term.Position, &term.IsStarted, &term.IsTerminal); err != nil {
+				rows.Close()
+				return vocabulary, err
+			}
+
+			*dest = append(*dest, term)
+		}
+
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return vocabulary, err
+		}
+	}
+
+	rows, err := db.Query("select value, label, position from priorities order by position, value")
+	if err != nil {
+		return vocabulary, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var priority Priority
+		if err := rows.Scan(&priority.Value, &priority.Label, &priority.Position); err != nil {
+			return vocabulary, err
+		}
+
+		vocabulary.Priorities = append(vocabulary.Priorities, priority)
+	}
+
+	return vocabulary, rows.Err()
+}
+
+// insert or update a term. Without a position the term keeps its place, or
+// goes last when it is new
+func saveTerm(db *sql.DB, kind string, term VocabularyTerm) (VocabularyTerm, error) {
+	table := termKinds[kind].table
+	if term.Position == 0 {
+		q := "select coalesce((select position from " + table + " where name = $1), (select coalesce(max(position), 0) + 1 from " + table + "))"
+		if err := db.QueryRow(q, term.Name).Scan(&term.Position); err != nil {
+			return VocabularyTerm{}, err
+		}
+	}
+
+	if termKinds[kind].terminal == "false" {
+		q := "insert into " + table + " (name, label, position) values ($1, $2, $3) on conflict (name) do update set label = excluded.label, position = excluded.position"
+		_, err := db.Exec(q, term.Name, term.Label, term.Position)
+		return term, err
+	}
+
+	q := "insert into " + table + " (name, label, position, is_started, is_terminal) values ($1, $2, $3, $4, $5) on conflict (name) do update set label = excluded.label, position = excluded.position, is_started = excluded.is_started, is_terminal = excluded.is_terminal"
+	_, err := db.Exec(q, term.Name, term.Label, term.Position, term.IsStarted, term.IsTerminal)
+	return term, err
+}
+
+// delete a term that no log uses
+func deleteTerm(db *sql.DB, kind string, name string) error {
+	var exists int
+	err := db.QueryRow("select 1 from logs where "+termKinds[kind].logColumn+" = $1 limit 1", name).Scan(&exists)
+	if err == nil {
+		return ErrTermInUse
+	}
+
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	result, err := db.Exec("delete from "+termKinds[kind].table+" where name = $1", name)
+	if err != nil {
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrTermNotFound
+	}
+
+	return nil
+}
+
+func savePriority(db *sql.DB, priority Priority) (Priority, error) {
+	if priority.Position == 0 {
+		q := "select coalesce((select position from priorities where value = $1), (select coalesce(max(position), 0) + 1 from priorities))"
+		if err := db.QueryRow(q, priority.Value).Scan(&priority.Position); err != nil {
+			return Priority{}, err
+		}
+	}
+
+	q := "insert into priorities (value, label, position) values ($1, $2, $3) on conflict (value) do update set label = excluded.label, position = excluded.position"
+	_, err := db.Exec(q, priority.Value, priority.Label, priority.Position)
+	return priority, err
+}
+
+func deletePriority(db *sql.DB, value int) error {
+	var exists int
+	err := db.QueryRow("select 1 from logs where priority = $1 limit 1", value).Scan(&exists)
+	if err == nil {
+		return ErrTermInUse
+	}
+
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	result, err := db.Exec("delete from priorities where value = $1", value)
+	if err != nil {
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ErrTermNotFound
+	}
+
+	return nil
+}
+
+// load the vocabulary for a request, writes the error response when it fails
+func loadVocabulary(store VocabularyStore, w http.ResponseWriter) (Vocabulary, bool) {
+	vocabulary, err := store.GetVocabulary()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Something wen't wrong while loading the allowed values"})
+		return vocabulary, false
+	}
+
+	return vocabulary, true
+}
+
+func handleGetMeta(store VocabularyStore, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vocabulary, ok := loadVocabulary(store, w)
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(vocabulary)
+}
+
+func handleSaveTerm(store VocabularyStore, workflow Workflow, kind string, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var body struct {
+		Label      string `json:"label"`
+		Position   int    `json:"position"`
+		IsStarted  *bool  `json:"isStarted"`
+		IsTerminal *bool  `json:"isTerminal"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	vocabulary, ok := loadVocabulary(store, w)
+	if !ok {
+		return
+	}
+
+	// only statuses can be started or terminal, a status keeps its flags when
+	// the body doesn't have them
+	isStarted, isTerminal := false, false
+	if kind == "task-statuses" {
+		isStarted = vocabulary.isStarted(r.PathValue("name"))
+		if body.IsStarted != nil {
+			isStarted = *body.IsStarted
+		}
+
+		isTerminal = vocabulary.isTerminal(r.PathValue("name"))
+		if body.IsTerminal != nil {
+			isTerminal = *body.IsTerminal
+		}
+	}
+
+	term := VocabularyTerm{Name: r.PathValue("name"), Label: strings.TrimSpace(body.Label), Position: body.Position, IsStarted: isStarted, IsTerminal: isTerminal}
+	if !termNamePattern.MatchString(term.Name) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid name, use lower case letters, digits, - and _"})
+		return
+	}
+
+	if term.Label == "" {
+		term.Label = term.Name
+	}
+
+	// a new status must be reachable in the loaded workflow
+	if kind == "task-statuses" && !vocabulary.validateTaskStatus(term.Name) && !workflow.canReach(term.Name, vocabulary) {
+		writeTermError(w, ErrTermUnreachable)
+		return
+	}
+
+	term, err = store.SaveTerm(kind, term)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Message string         `json:"message"`
+		Term    VocabularyTerm `json:"term"`
+	}{Message: "Saved successfully", Term: term})
+}
+
+func handleDeleteTerm(store VocabularyStore, workflow Workflow, kind string, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	// the loaded workflow must keep naming known statuses only
+	if kind == "task-statuses" && workflow.uses(r.PathValue("name")) {
+		writeTermError(w, ErrTermInWorkflow)
+		return
+	}
+
+	err := store.DeleteTerm(kind, r.PathValue("name"))
+	if err != nil {
+		writeTermError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Successfully deleted"})
+}
+
+func handleSavePriority(store VocabularyStore, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var body struct {
+		Label    string `json:"label"`
+		Position int    `json:"position"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	value, err := strconv.Atoi(r.PathValue("value"))
+	if err != nil || value < 1 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid priority value"})
+		return
+	}
+
+	priority := Priority{Value: value, Label: strings.TrimSpace(body.Label), Position: body.Position}
+	if priority.Label == "" {
+		priority.Label = strconv.Itoa(value)
+	}
+
+	priority, err = store.SavePriority(priority)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Message  string   `json:"message"`
+		Priority Priority `json:"priority"`
+	}{Message: "Saved successfully", Priority: priority})
+}
+
+func handleDeletePriority(store VocabularyStore, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	value, err := strconv.Atoi(r.PathValue("value"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": ErrTermNotFound.Error()})
+		return
+	}
+
+	if err := store.DeletePriority(value); err != nil {
+		writeTermError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Successfully deleted"})
+}
+
+func writeTermError(w http.ResponseWriter, err error) {
+	switch err {
+	case ErrTermNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case ErrTermInUse, ErrTermInWorkflow, ErrTermUnreachable:
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+}
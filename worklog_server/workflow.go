@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// statuses a log can move to from each status, and what happens on the way.
+// A status missing from the transitions can move anywhere
+type Workflow struct {
+	Transitions map[string][]string `json:"transitions"`
+	Hooks       []WorkflowHook      `json:"hooks"`
+}
+
+// a change made to a log when it enters or leaves a status. Set puts the
+// current time in the field, or only when it is empty with ifNull. Clear
+// removes the value
+type WorkflowHook struct {
+	Status string `json:"status"`
+	On     string `json:"on"`
+	Action string `json:"action"`
+	Field  string `json:"field"`
+	IfNull bool   `json:"ifNull"`
+}
+
+// workflow used when WORKFLOW_FILE isn't set, any status of the vocabulary
+// can move to any other one
+var defaultWorkflow = Workflow{
+	Transitions: map[string][]string{},
+	Hooks:       []WorkflowHook{},
+}
+
+// read the workflow from a json file, the default workflow without one. The
+// statuses must be in the vocabulary
+func LoadWorkflow(path string, vocabulary Vocabulary) (Workflow, error) {
+	if path == "" {
+		return defaultWorkflow, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Workflow{}, err
+	}
+
+	var workflow Workflow
+	if err := json.Unmarshal(data, &workflow); err != nil {
+		return Workflow{}, fmt.Errorf("invalid workflow file %s: %w", path, err)
+	}
+
+	if err := workflow.validate(vocabulary); err != nil {
+		return Workflow{}, fmt.Errorf("invalid workflow file %s: %w", path, err)
+	}
+
+	return workflow, nil
+}
+
+func (workflow *Workflow) validate(vocabulary Vocabulary) error {
+	for from, targets := range workflow.Transitions {
+		if !vocabulary.validateTaskStatus(from) {
+			return fmt.Errorf("unknown status %q", from)
+		}
+
+		for _, to := range targets {
+			if !vocabulary.validateTaskStatus(to) {
+				return fmt.Errorf("unknown status %q", to)
+			}
+		}
+	}
+
+	for i, hook := range workflow.Hooks {
+		if !vocabulary.validateTaskStatus(hook.Status) {
+			return fmt.Errorf("unknown status %q in hook %d", hook.Status, i)
+		}
+
+		if hook.On == "" {
+			workflow.Hooks[i].On = "enter"
+		} else if hook.On != "enter" && hook.On != "leave" {
+			return fmt.Errorf("hook %d: on must be enter or leave", i)
+		}
+
+		if hook.Action != "set" && hook.Action != "clear" {
+			return fmt.Errorf("hook %d: action must be set or clear", i)
+		}
+
+		if hook.Field != "startedAt" && hook.Field != "completedAt" {
+			return fmt.Errorf("hook %d: field must be startedAt or completedAt", i)
+		}
+	}
+
+	return nil
+}
+
+// check if a log can move from a status to another one
+func (workflow Workflow) canTransition(from string, to string) bool {
+	targets, ok := workflow.Transitions[from]
+	if !ok || from == to {
+		return true
+	}
+
+	for _, target := range targets {
+		if target == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// check if the transitions or the hooks name a status
+func (workflow Workflow) uses(status string) bool {
+	if _, ok := workflow.Transitions[status]; ok {
+		return true
+	}
+
+	for _, targets := range workflow.Transitions {
+		for _, target := range targets {
+			if target == status {
+				return true
+			}
+		}
+	}
+
+	for _, hook := range workflow.Hooks {
+		if hook.Status == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// check if a log in one of the statuses of the vocabulary can move to a new
+// status, which is missing from the transitions
+func (workflow Workflow) canReach(status string, vocabulary Vocabulary) bool {
+	for _, term := range vocabulary.TaskStatuses {
+		if term.Name != status && workflow.canTransition(term.Name, status) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// message explaining why a transition was rejected
+func (workflow Workflow) transitionError(from string, to string) string {
+	targets := workflow.Transitions[from]
+	if len(targets) == 0 {
+		return fmt.Sprintf("A log in %s can't change status", from)
+	}
+
+	return fmt.Sprintf("A log can't move from %s to %s, allowed statuses: %s", from, to, strings.Join(targets, ", "))
+}
+
+// run the lifecycle and the hooks of a move from a status to another one on
+// the times of a log, from is empty for a new log. Entering a started status
+// sets startedAt when it is empty, entering a terminal status sets completedAt
+// the same way and leaving one clears it. The hooks run after the lifecycle.
+// Returns the times after the hooks
+func (workflow Workflow) runHooks(vocabulary Vocabulary, from string, to string, startedAt *time.Time, completedAt *time.Time, now time.Time) (*time.Time, *time.Time) {
+	if from == to {
+		return startedAt, completedAt
+	}
+
+	if vocabulary.isStarted(to) && startedAt == nil {
+		value := now
+		startedAt = &value
+	}
+
+	if vocabulary.isTerminal(to) && !vocabulary.isTerminal(from) && completedAt == nil {
+		value := now
+		completedAt = &value
+	}
+
+	if vocabulary.isTerminal(from) && !vocabulary.isTerminal(to) {
+		completedAt = nil
+	}
+
+	fields := map[string]**time.Time{"startedAt": &startedAt, "completedAt": &completedAt}
+	for _, hook := range workflow.Hooks {
+		if (hook.On == "leave" && hook.Status != from) || (hook.On != "leave" && hook.Status != to) {
+			continue
+		}
+
+		field := fields[hook.Field]
+		switch hook.Action {
+		case "set":
+			if *field == nil || !hook.IfNull {
+				value := now
+				*field = &value
+			}
+		case "clear":
+			*field = nil
+		}
+	}
+
+	return startedAt, completedAt
+}
+
+func handleGetWorkflow(workflow Workflow, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		Workflow Workflow `json:"workflow"`
+	}{Workflow: workflow})
+}